@@ -1,28 +1,128 @@
 package config
 
-import "github.com/sirupsen/logrus"
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultTenant = "default"
 
 type Common struct {
 	LogLevel                             logrus.Level
 	DatabaseURL                          string
-	MainRepository                       string
+	MgoURI                               string
+	Tenants                              []Tenant
 	TaskRepositoryOrgContributorsToCheck int64
+	EligibilityStrategies                []string
+	Timezone                             string
+	WebhookAllowLocalAddresses           bool
+}
+
+// Tenant returns the tenant for given name.
+func (c Common) Tenant(name string) (Tenant, bool) {
+	for i := range c.Tenants {
+		if c.Tenants[i].Name == name {
+			return c.Tenants[i], true
+		}
+	}
+	return Tenant{}, false
+}
+
+// IsMainRepository checks if given repository is the main repository of one of the tenants.
+func (c Common) IsMainRepository(repo string) bool {
+	for i := range c.Tenants {
+		if strings.EqualFold(c.Tenants[i].MainRepository, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Location returns the location for the deployment's timezone, UTC if empty or invalid.
+func (c Common) Location() *time.Location {
+	if c.Timezone != "" {
+		if loc, err := time.LoadLocation(c.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
 }
 
 type Crawler struct {
 	Common
 	GHToken                         string
-	MgoURI                          string
 	UserExpirationDelay             int64
 	MainRepositoryScanDelay         int64
 	TaskRepositoryScanDelay         int64
 	TaskRepositoryMaxStargazerPages int64
-	TaskRepositoryExclusions        []string
+	TaskRepositoryEnrichUsers       bool
+	TaskRepositoryEnrichUsersCount  int64
+	TaskRepositoryEnrichUsersShare  float64
+	StatsSpikeWindowDays            int64
+	StatsSpikeThreshold             float64
+	StatsReleaseWindowDays          int64
+	StatsSnapshotsRetentionDays     int64
+	WebhookDeliveryScanDelay        int64
+	WebhookMaxAttempts              int64
+	WebhookRetryDelay               int64
 }
 
 type Web struct {
 	Common
 	Port            int64
 	RegenerateDelay int64
-	MaxEntriesCount int64
+	APICORSOrigins  []string
+	TrustedProxies  []string
+}
+
+// Tenant is a gate repository with its own stargazers, exclusions and entries quota.
+type Tenant struct {
+	Name                     string   `json:"name"`
+	Title                    string   `json:"title"`
+	Hosts                    []string `json:"hosts"`
+	MainRepository           string   `json:"main_repository"`
+	TaskRepositoryExclusions []string `json:"task_repository_exclusions"`
+	MaxEntriesCount          int64    `json:"max_entries_count"`
+}
+
+// IsExcluded checks if given repository is excluded from computing for the tenant.
+func (t Tenant) IsExcluded(repo string) bool {
+	for i := range t.TaskRepositoryExclusions {
+		if strings.EqualFold(t.TaskRepositoryExclusions[i], repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTenants reads additional tenants from a JSON file.
+func LoadTenants(path string) ([]Tenant, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't read tenants file %s", path)
+	}
+
+	var ts []Tenant
+	if err := json.Unmarshal(buf, &ts); err != nil {
+		return nil, errors.Wrapf(err, "can't parse tenants file %s", path)
+	}
+
+	for i := range ts {
+		if ts[i].Name == "" || ts[i].MainRepository == "" {
+			return nil, errors.Errorf("missing name or main repository for tenant at index %d", i)
+		}
+		if ts[i].Name == DefaultTenant {
+			return nil, errors.Errorf("tenant name %s is reserved", DefaultTenant)
+		}
+		if ts[i].Title == "" {
+			ts[i].Title = "Stargazer"
+		}
+	}
+
+	return ts, nil
 }
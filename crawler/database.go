@@ -9,6 +9,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/richardlt/stargazer/config"
 )
 
 func NewMongoClient(db *mongo.Database) *DatabaseClient {
@@ -22,6 +24,20 @@ type DatabaseClient struct {
 func (c *DatabaseClient) Init() error {
 	coStargazers := c.db.Collection("stargazers")
 	coUsers := c.db.Collection("users")
+	coReleases := c.db.Collection("releases")
+	coRepositorySnapshots := c.db.Collection("repository_snapshots")
+	coCrawlSessions := c.db.Collection("crawl_sessions")
+
+	// Documents crawled before tenants were stored belong to the default tenant
+	for _, name := range []string{"repositories", "stargazers", "users", "releases", "repository_snapshots", "crawl_sessions"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		_, err := c.db.Collection(name).UpdateMany(ctx, bson.M{"tenant": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"tenant": config.DefaultTenant}})
+		cancel()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -35,8 +51,44 @@ func (c *DatabaseClient) Init() error {
 	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if _, err := coStargazers.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "repository_path", Value: 1}},
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
 	if _, err := coUsers.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys: bson.M{"login": -1},
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "login", Value: -1}},
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := coReleases.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "repository_path", Value: 1}},
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := coRepositorySnapshots.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "repository_path", Value: 1}, {Key: "date", Value: 1}},
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := coCrawlSessions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "repository_path", Value: 1}, {Key: "started_at", Value: -1}},
 	}); err != nil {
 		return errors.WithStack(err)
 	}
@@ -44,14 +96,14 @@ func (c *DatabaseClient) Init() error {
 	return nil
 }
 
-func (c DatabaseClient) getRepository(path string) (*repository, error) {
+func (c DatabaseClient) getRepository(tenant, path string) (*repository, error) {
 	co := c.db.Collection("repositories")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var r repository
-	if err := co.FindOne(ctx, bson.M{"path": path}).Decode(&r); err != nil {
+	if err := co.FindOne(ctx, bson.M{"tenant": tenant, "path": path}).Decode(&r); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
@@ -82,6 +134,38 @@ func (c DatabaseClient) updateRepository(r *repository) error {
 	return errors.WithStack(err)
 }
 
+func (c DatabaseClient) insertRepositorySnapshot(s *repositorySnapshot) error {
+	co := c.db.Collection("repository_snapshots")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.ID = primitive.NewObjectID()
+	_, err := co.InsertOne(ctx, s)
+	return errors.WithStack(err)
+}
+
+func (c DatabaseClient) getRepositorySnapshots(tenant, repo string) ([]repositorySnapshot, error) {
+	co := c.db.Collection("repository_snapshots")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "repository_path": repo}, &options.FindOptions{
+		Sort: bson.M{"date": 1},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var ss []repositorySnapshot
+	if err := cur.All(ctx, &ss); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return ss, nil
+}
+
 func (c DatabaseClient) countStargazers(repositoryID primitive.ObjectID) (int64, error) {
 	co := c.db.Collection("stargazers")
 
@@ -92,13 +176,13 @@ func (c DatabaseClient) countStargazers(repositoryID primitive.ObjectID) (int64,
 	return count, errors.WithStack(err)
 }
 
-func (c DatabaseClient) getStargazers(repo string) ([]stargazer, error) {
+func (c DatabaseClient) getStargazers(tenant, repo string) ([]stargazer, error) {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cur, err := co.Find(ctx, bson.M{"repository_path": repo}, &options.FindOptions{
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "repository_path": repo}, &options.FindOptions{
 		Sort: bson.M{"data.starred_at": -1},
 	})
 	if err != nil {
@@ -117,56 +201,202 @@ func (c DatabaseClient) getStargazers(repo string) ([]stargazer, error) {
 	return ss, nil
 }
 
-func (c DatabaseClient) getLast10Stargazers(repo string) ([]stargazer, error) {
+func (c DatabaseClient) getLastStargazers(tenant, repo string, limit int64) ([]stargazer, error) {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	query := []bson.M{
-		{
-			"$match": bson.M{"repository_path": repo, "last_page": true},
-		},
-		{
-			"$sort": bson.M{"data.starred_at": -1},
-		},
-		{
-			"$limit": 10,
-		},
-	}
-
-	res, err := co.Aggregate(ctx, query)
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "repository_path": repo}, &options.FindOptions{
+		Sort:  bson.M{"data.starred_at": -1},
+		Limit: &limit,
+	})
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
 	var ss []stargazer
-	if err := res.All(ctx, &ss); err != nil {
+	if err := cur.All(ctx, &ss); err != nil {
 		return nil, errors.WithStack(err)
 	}
 
 	return ss, nil
 }
 
-func (c DatabaseClient) deleteStargazers(repositoryID primitive.ObjectID) error {
+func (c DatabaseClient) insertStargazers(ss []stargazer) error {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := co.DeleteMany(ctx, bson.M{"_repository_id": repositoryID})
+	for i := range ss {
+		ss[i].ID = primitive.NewObjectID()
+		if _, err := co.InsertOne(ctx, ss[i]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func (c DatabaseClient) countStargazersPage(repositoryID primitive.ObjectID, page int64) (int64, error) {
+	co := c.db.Collection("stargazers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := co.CountDocuments(ctx, bson.M{"_repository_id": repositoryID, "page": page})
+	return count, errors.WithStack(err)
+}
+
+// replaceStargazersPage replaces stored stargazers for a page of a repository.
+func (c DatabaseClient) replaceStargazersPage(repositoryID primitive.ObjectID, page int64, ss []stargazer) error {
+	co := c.db.Collection("stargazers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := co.DeleteMany(ctx, bson.M{"_repository_id": repositoryID, "page": page}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.insertStargazers(ss)
+}
+
+// deleteStargazersExceptPages removes stargazers from pages that were not loaded during the last crawl.
+func (c DatabaseClient) deleteStargazersExceptPages(repositoryID primitive.ObjectID, pages []int64) error {
+	co := c.db.Collection("stargazers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := co.DeleteMany(ctx, bson.M{"_repository_id": repositoryID, "page": bson.M{"$nin": pages}})
 	return errors.WithStack(err)
 }
 
-func (c DatabaseClient) insertStargazers(ss []stargazer) error {
+func (c DatabaseClient) updateStargazersLastPage(repositoryID primitive.ObjectID, lastPage int64) error {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	for i := range ss {
-		ss[i].ID = primitive.NewObjectID()
-		if _, err := co.InsertOne(ctx, ss[i]); err != nil {
+	if _, err := co.UpdateMany(ctx, bson.M{"_repository_id": repositoryID, "page": bson.M{"$ne": lastPage}},
+		bson.M{"$set": bson.M{"last_page": false}}); err != nil {
+		return errors.WithStack(err)
+	}
+	_, err := co.UpdateMany(ctx, bson.M{"_repository_id": repositoryID, "page": lastPage},
+		bson.M{"$set": bson.M{"last_page": true}})
+	return errors.WithStack(err)
+}
+
+// getLastCrawlSession returns the last crawl session for a repository, only completed ones if asked.
+func (c DatabaseClient) getLastCrawlSession(tenant, repo string, completed bool) (*crawlSession, error) {
+	co := c.db.Collection("crawl_sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tenant": tenant, "repository_path": repo}
+	if completed {
+		filter["completed_at"] = bson.M{"$ne": nil}
+	}
+
+	var s crawlSession
+	if err := co.FindOne(ctx, filter, &options.FindOneOptions{
+		Sort: bson.M{"started_at": -1},
+	}).Decode(&s); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return &s, nil
+}
+
+func (c DatabaseClient) insertCrawlSession(s *crawlSession) error {
+	co := c.db.Collection("crawl_sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.ID = primitive.NewObjectID()
+	_, err := co.InsertOne(ctx, s)
+	return errors.WithStack(err)
+}
+
+// addCrawlSessionPage checkpoints a page loaded during a crawl session.
+func (c DatabaseClient) addCrawlSessionPage(sessionID primitive.ObjectID, p crawlPage) error {
+	co := c.db.Collection("crawl_sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := co.UpdateOne(ctx, bson.M{"_id": sessionID}, bson.M{"$push": bson.M{"pages": p}})
+	return errors.WithStack(err)
+}
+
+// completeCrawlSession marks the session as completed and deletes the previous sessions for the repository that are
+// not needed anymore.
+func (c DatabaseClient) completeCrawlSession(s *crawlSession) error {
+	co := c.db.Collection("crawl_sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := co.UpdateOne(ctx, bson.M{"_id": s.ID}, bson.M{"$set": bson.M{"completed_at": time.Now()}}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err := co.DeleteMany(ctx, bson.M{
+		"tenant":          s.Tenant,
+		"repository_path": s.RepositoryPath,
+		"_id":             bson.M{"$ne": s.ID},
+		"started_at":      bson.M{"$lte": s.StartedAt},
+	})
+	return errors.WithStack(err)
+}
+
+func (c DatabaseClient) getReleases(tenant, repo string) ([]release, error) {
+	co := c.db.Collection("releases")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "repository_path": repo}, &options.FindOptions{
+		Sort: bson.M{"data.published_at": 1},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var rs []release
+	if err := cur.All(ctx, &rs); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return rs, nil
+}
+
+func (c DatabaseClient) deleteReleases(repositoryID primitive.ObjectID) error {
+	co := c.db.Collection("releases")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := co.DeleteMany(ctx, bson.M{"_repository_id": repositoryID})
+	return errors.WithStack(err)
+}
+
+func (c DatabaseClient) insertReleases(rs []release) error {
+	co := c.db.Collection("releases")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := range rs {
+		rs[i].ID = primitive.NewObjectID()
+		if _, err := co.InsertOne(ctx, rs[i]); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -174,14 +404,14 @@ func (c DatabaseClient) insertStargazers(ss []stargazer) error {
 	return nil
 }
 
-func (c DatabaseClient) getUser(login string) (*user, error) {
+func (c DatabaseClient) getUser(tenant, login string) (*user, error) {
 	co := c.db.Collection("users")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var u user
-	if err := co.FindOne(ctx, bson.M{"login": login}).Decode(&u); err != nil {
+	if err := co.FindOne(ctx, bson.M{"tenant": tenant, "login": login}).Decode(&u); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
@@ -191,6 +421,25 @@ func (c DatabaseClient) getUser(login string) (*user, error) {
 	return &u, nil
 }
 
+func (c DatabaseClient) getUsers(tenant string, logins []string) ([]user, error) {
+	co := c.db.Collection("users")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "login": bson.M{"$in": logins}})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var us []user
+	if err := cur.All(ctx, &us); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return us, nil
+}
+
 func (c DatabaseClient) insertUser(u *user) error {
 	co := c.db.Collection("users")
 
@@ -212,14 +461,24 @@ func (c DatabaseClient) updateUser(u *user) error {
 	return errors.WithStack(err)
 }
 
-func (c DatabaseClient) existsOneOfRepositoryStargazer(repo string, logins ...string) (bool, error) {
+func (c DatabaseClient) updateUserExpire(id primitive.ObjectID, expire time.Time) error {
+	co := c.db.Collection("users")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := co.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"expire": expire}})
+	return errors.WithStack(err)
+}
+
+func (c DatabaseClient) existsOneOfRepositoryStargazer(tenant, repo string, logins ...string) (bool, error) {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	res, err := co.Aggregate(ctx, []bson.M{
-		{"$match": bson.M{"repository_path": repo}},
+		{"$match": bson.M{"tenant": tenant, "repository_path": repo}},
 		{
 			"$lookup": bson.M{
 				"from":         "users",
@@ -230,8 +489,11 @@ func (c DatabaseClient) existsOneOfRepositoryStargazer(repo string, logins ...st
 		},
 		{
 			"$project": bson.M{
-				"_id":  "$_id",
-				"user": bson.M{"$arrayElemAt": []interface{}{"$users", 0}},
+				"_id": "$_id",
+				"user": bson.M{"$arrayElemAt": []interface{}{bson.M{"$filter": bson.M{
+					"input": "$users",
+					"cond":  bson.M{"$eq": []interface{}{"$$this.tenant", tenant}},
+				}}, 0}},
 			},
 		},
 		{
@@ -295,7 +557,19 @@ func (c DatabaseClient) existsOneOfRepositoryStargazer(repo string, logins ...st
 	return len(all) > 0, nil
 }
 
-func (c DatabaseClient) getRepoStarCountPerDaysAndPage(repo string) ([]measure, error) {
+// dayInTimezone returns an expression that truncates given date field to the start of its day in the timezone.
+func dayInTimezone(field, timezone string) bson.M {
+	return bson.M{
+		"$dateFromString": bson.M{
+			"dateString": bson.M{
+				"$dateToString": bson.M{"format": "%Y-%m-%d", "date": field, "timezone": timezone},
+			},
+			"timezone": timezone,
+		},
+	}
+}
+
+func (c DatabaseClient) getRepoStarCountPerDaysAndPage(tenant, repo, timezone string) ([]measure, error) {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -303,29 +577,13 @@ func (c DatabaseClient) getRepoStarCountPerDaysAndPage(repo string) ([]measure,
 
 	query := []bson.M{
 		{
-			"$match": bson.M{"repository_path": repo},
+			"$match": bson.M{"tenant": tenant, "repository_path": repo},
 		},
 		{
 			"$project": bson.M{
 				"page":       "$page",
 				"starred_at": "$data.starred_at",
-				"year":       bson.M{"$toString": bson.M{"$year": "$data.starred_at"}},
-				"month":      bson.M{"$toString": bson.M{"$month": "$data.starred_at"}},
-				"day":        bson.M{"$toString": bson.M{"$dayOfMonth": "$data.starred_at"}},
-				"hour":       bson.M{"$toString": bson.M{"$hour": "$data.starred_at"}},
-			},
-		},
-		{
-			"$project": bson.M{
-				"page":       "$page",
-				"starred_at": "$starred_at",
-				"date": bson.M{
-					"$dateFromString": bson.M{
-						"dateString": bson.M{
-							"$concat": []interface{}{"$year", "-", "$month", "-", "$day"},
-						},
-					},
-				},
+				"date":       dayInTimezone("$data.starred_at", timezone),
 			},
 		},
 		{"$sort": bson.M{"page": 1, "starred_at": 1}},
@@ -351,7 +609,8 @@ func (c DatabaseClient) getRepoStarCountPerDaysAndPage(repo string) ([]measure,
 	return ms, nil
 }
 
-func (c DatabaseClient) getRepoStarCountPerDays(repo string) ([]measure, error) {
+// getRepoStarCountPerHours returns the count of stars per UTC hour for the stargazers of the last page.
+func (c DatabaseClient) getRepoStarCountPerHours(tenant, repo string) ([]measure, error) {
 	co := c.db.Collection("stargazers")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -359,27 +618,17 @@ func (c DatabaseClient) getRepoStarCountPerDays(repo string) ([]measure, error)
 
 	query := []bson.M{
 		{
-			"$match": bson.M{"repository_path": repo, "last_page": true},
+			"$match": bson.M{"tenant": tenant, "repository_path": repo, "last_page": true},
 		},
 		{
 			"$project": bson.M{
 				"starred_at": "$data.starred_at",
-				"year":       bson.M{"$toString": bson.M{"$year": "$data.starred_at"}},
-				"month":      bson.M{"$toString": bson.M{"$month": "$data.starred_at"}},
-				"day":        bson.M{"$toString": bson.M{"$dayOfMonth": "$data.starred_at"}},
-				"hour":       bson.M{"$toString": bson.M{"$hour": "$data.starred_at"}},
-			},
-		},
-		{
-			"$project": bson.M{
-				"starred_at": "$starred_at",
-				"date": bson.M{
-					"$dateFromString": bson.M{
-						"dateString": bson.M{
-							"$concat": []interface{}{"$year", "-", "$month", "-", "$day"},
-						},
-					},
-				},
+				"date": bson.M{"$dateFromParts": bson.M{
+					"year":  bson.M{"$year": "$data.starred_at"},
+					"month": bson.M{"$month": "$data.starred_at"},
+					"day":   bson.M{"$dayOfMonth": "$data.starred_at"},
+					"hour":  bson.M{"$hour": "$data.starred_at"},
+				}},
 			},
 		},
 		{"$sort": bson.M{"starred_at": -1}},
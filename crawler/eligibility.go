@@ -0,0 +1,190 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/crawler/github"
+)
+
+const (
+	EligibilityStrategyStar  = "star"
+	EligibilityStrategyFile  = "file"
+	EligibilityStrategyTopic = "topic"
+	EligibilityStrategyBadge = "badge"
+
+	eligibilityFile  = ".stargazer"
+	eligibilityTopic = "stargazer"
+)
+
+// EligibilityChecker checks if stats can be computed for a task repository. The repository is loaded from GH only by
+// checkers that need it.
+type EligibilityChecker interface {
+	IsEligible(path string, loadRepository func() (github.Repository, error)) (bool, error)
+}
+
+// NewEligibilityChecker returns a checker for the tenant that accepts a repository if one of the given strategies accepts it.
+// The star strategy is checked first as it is mostly checked from the database.
+func NewEligibilityChecker(mgoClient *DatabaseClient, ghClient github.Client, cfg config.Crawler, tenant config.Tenant) (EligibilityChecker, error) {
+	strategies := cfg.EligibilityStrategies
+	if len(strategies) == 0 {
+		strategies = []string{EligibilityStrategyStar}
+	}
+
+	var cs anyEligibilityChecker
+	for _, s := range strategies {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case EligibilityStrategyStar:
+			cs = append(anyEligibilityChecker{starEligibilityChecker{
+				mgoClient:              mgoClient,
+				ghClient:               ghClient,
+				tenant:                 tenant.Name,
+				mainRepository:         tenant.MainRepository,
+				orgContributorsToCheck: cfg.TaskRepositoryOrgContributorsToCheck,
+			}}, cs...)
+		case EligibilityStrategyFile:
+			cs = append(cs, fileEligibilityChecker{ghClient: ghClient, file: eligibilityFile})
+		case EligibilityStrategyTopic:
+			cs = append(cs, topicEligibilityChecker{ghClient: ghClient, topic: eligibilityTopic})
+		case EligibilityStrategyBadge:
+			cs = append(cs, badgeEligibilityChecker{ghClient: ghClient, link: badgeLinkRegexp(tenant.MainRepository)})
+		default:
+			return nil, errors.Errorf("invalid eligibility strategy %s", s)
+		}
+	}
+
+	return cs, nil
+}
+
+// NewEligibilityCheckers returns eligibility checkers for all tenants by tenant name.
+func NewEligibilityCheckers(mgoClient *DatabaseClient, ghClient github.Client, cfg config.Crawler) (map[string]EligibilityChecker, error) {
+	cs := make(map[string]EligibilityChecker, len(cfg.Tenants))
+	for _, t := range cfg.Tenants {
+		c, err := NewEligibilityChecker(mgoClient, ghClient, cfg, t)
+		if err != nil {
+			return nil, err
+		}
+		cs[t.Name] = c
+	}
+	return cs, nil
+}
+
+type anyEligibilityChecker []EligibilityChecker
+
+// IsEligible tries all the checkers until one accepts the repository, an error is only returned if none accepted it.
+func (a anyEligibilityChecker) IsEligible(path string, loadRepository func() (github.Repository, error)) (bool, error) {
+	var errs []error
+	for i := range a {
+		ok, err := a[i].IsEligible(path, loadRepository)
+		if err != nil {
+			logrus.Debugf("anyEligibilityChecker: strategy %d failed for %s: %v", i, path, err)
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	if len(errs) > 0 {
+		return false, errs[0]
+	}
+	return false, nil
+}
+
+// starEligibilityChecker checks that the repository owner starred the main repository.
+// For organization repository, one of the top contributors should also have starred the main repository.
+type starEligibilityChecker struct {
+	mgoClient              *DatabaseClient
+	ghClient               github.Client
+	tenant                 string
+	mainRepository         string
+	orgContributorsToCheck int64
+}
+
+func (s starEligibilityChecker) IsEligible(path string, loadRepository func() (github.Repository, error)) (bool, error) {
+	owner := strings.Split(path, "/")[0]
+
+	// For organization repository, first check that one stargazer of the main repository is in the organization
+	exists, err := s.mgoClient.existsOneOfRepositoryStargazer(s.tenant, s.mainRepository, strings.ToLower(owner))
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		logrus.Debugf("starEligibilityChecker: no stargazer found on main repo for %s", path)
+		return false, nil
+	}
+
+	ghRepo, err := loadRepository()
+	if err != nil {
+		return false, err
+	}
+	if ghRepo.Owner.Type != "Organization" {
+		return true, nil
+	}
+
+	logrus.Debugf("starEligibilityChecker: repository owner is an organization, checking contributors for %s", ghRepo.FullName)
+	contributors, err := s.ghClient.GetRepositoryConributors(ghRepo.FullName)
+	if err != nil || len(contributors) == 0 {
+		logrus.Debugf("starEligibilityChecker: repository contributors not found on GH %s", ghRepo.FullName)
+		return false, nil
+	}
+	var logins []string
+	for i := 0; i < int(s.orgContributorsToCheck) && i < len(contributors); i++ {
+		logins = append(logins, strings.ToLower(contributors[i].Login))
+	}
+
+	// For organization repository we check that one of the top contributors starred the main repository
+	return s.mgoClient.existsOneOfRepositoryStargazer(s.tenant, s.mainRepository, logins...)
+}
+
+// fileEligibilityChecker checks that the repository contains a verification file.
+type fileEligibilityChecker struct {
+	ghClient github.Client
+	file     string
+}
+
+func (f fileEligibilityChecker) IsEligible(path string, _ func() (github.Repository, error)) (bool, error) {
+	return f.ghClient.HasRepositoryFile(path, f.file)
+}
+
+// topicEligibilityChecker checks that the repository has a verification topic.
+type topicEligibilityChecker struct {
+	ghClient github.Client
+	topic    string
+}
+
+func (t topicEligibilityChecker) IsEligible(path string, _ func() (github.Repository, error)) (bool, error) {
+	topics, err := t.ghClient.GetRepositoryTopics(path)
+	if err != nil {
+		return false, err
+	}
+	for i := range topics {
+		if strings.EqualFold(topics[i], t.topic) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// badgeEligibilityChecker checks that the repository's README contains a link to the main repository.
+type badgeEligibilityChecker struct {
+	ghClient github.Client
+	link     *regexp.Regexp
+}
+
+// badgeLinkRegexp matches links to the repository but not to other repositories with the same prefix.
+func badgeLinkRegexp(repo string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)github\.com/` + regexp.QuoteMeta(repo) + `($|[^a-z0-9_.-]|\.($|[^a-z0-9_-]))`)
+}
+
+func (b badgeEligibilityChecker) IsEligible(path string, _ func() (github.Repository, error)) (bool, error) {
+	readme, err := b.ghClient.GetRepositoryReadme(path)
+	if err != nil {
+		return false, err
+	}
+	return b.link.MatchString(readme), nil
+}
@@ -0,0 +1,175 @@
+// Package geo resolves free-text locations to countries using an embedded gazetteer, without any external service.
+package geo
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed gazetteer.tsv
+var gazetteerData string
+
+//go:embed countries.tsv
+var countriesData string
+
+// usStates are the two letters codes of US states, often given after a city (ex: "Springfield, IL").
+var usStates = map[string]bool{
+	"al": true, "ak": true, "az": true, "ar": true, "ca": true, "co": true, "ct": true, "de": true, "dc": true, "fl": true,
+	"ga": true, "hi": true, "id": true, "il": true, "in": true, "ia": true, "ks": true, "ky": true, "la": true, "me": true,
+	"md": true, "ma": true, "mi": true, "mn": true, "ms": true, "mo": true, "mt": true, "ne": true, "nv": true, "nh": true,
+	"nj": true, "nm": true, "ny": true, "nc": true, "nd": true, "oh": true, "ok": true, "or": true, "pa": true, "ri": true,
+	"sc": true, "sd": true, "tn": true, "tx": true, "ut": true, "vt": true, "va": true, "wa": true, "wv": true, "wi": true,
+	"wy": true,
+}
+
+var (
+	loadOnce  sync.Once
+	gazetteer map[string]string
+	countries map[string]string
+)
+
+func load() {
+	gazetteer = parseTSV(gazetteerData)
+	countries = parseTSV(countriesData)
+}
+
+func parseTSV(data string) map[string]string {
+	m := make(map[string]string)
+	s := bufio.NewScanner(strings.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m
+}
+
+// CountryName returns the English name for given ISO 3166-1 alpha-2 country code.
+func CountryName(code string) string {
+	loadOnce.Do(load)
+	if name, ok := countries[strings.ToUpper(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// Lookup returns the ISO 3166-1 alpha-2 country code for given free-text location (ex: "Paris, France", "SF Bay Area").
+func Lookup(location string) (string, bool) {
+	loadOnce.Do(load)
+
+	location = normalize(location)
+	if location == "" {
+		return "", false
+	}
+	if code, ok := gazetteer[location]; ok {
+		return code, true
+	}
+
+	parts := strings.FieldsFunc(location, func(r rune) bool {
+		return r == ',' || r == '/' || r == '|' || r == ';' || r == '(' || r == ')' || r == '·' || r == '•'
+	})
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	// US state codes are checked first as a city name can match a place elsewhere (ex: "Paris, TX") and state codes
+	// can match a country code (ex: "Springfield, IL")
+	if code, ok := lookupUSState(parts); ok {
+		return code, true
+	}
+
+	// Most specific information is usually at the end (ex: "Lyon, France"), so look at parts from right to left
+	for i := len(parts) - 1; i >= 0; i-- {
+		if code, ok := gazetteer[parts[i]]; ok && len(parts[i]) > 2 {
+			return code, true
+		}
+	}
+	for i := len(parts) - 1; i >= 0; i-- {
+		if code, ok := lookupWords(strings.Fields(parts[i])); ok {
+			return code, true
+		}
+	}
+
+	// Fallback on two letters parts that match a country code (ex: "Berlin, DE")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if len(parts[i]) == 2 {
+			code := strings.ToUpper(parts[i])
+			if _, ok := countries[code]; ok {
+				return code, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// lookupUSState returns US if the last part that is not a place in US is a state code given after a place. The place
+// before the state code is kept if its country has the same code (ex: "Berlin, DE").
+func lookupUSState(parts []string) (string, bool) {
+	for i := len(parts) - 1; i >= 0; i-- {
+		if !usStates[parts[i]] {
+			if code, ok := gazetteer[parts[i]]; ok && code == "US" {
+				continue
+			}
+			return "", false
+		}
+		if i == 0 {
+			// A code alone is more likely a country code (ex: "DE")
+			return "", false
+		}
+		for j := i - 1; j >= 0; j-- {
+			code, ok := gazetteer[parts[j]]
+			if !ok || len(parts[j]) <= 2 {
+				code, ok = lookupWords(strings.Fields(parts[j]))
+			}
+			if ok {
+				if code == strings.ToUpper(parts[i]) {
+					return code, true
+				}
+				break
+			}
+		}
+		return "US", true
+	}
+	return "", false
+}
+
+// lookupWords search for the longest sequence of words that matches a place, starting from the end.
+func lookupWords(words []string) (string, bool) {
+	for size := len(words); size > 0; size-- {
+		for start := len(words) - size; start >= 0; start-- {
+			candidate := strings.Join(words[start:start+size], " ")
+			if len(candidate) <= 2 {
+				continue
+			}
+			if code, ok := gazetteer[candidate]; ok {
+				return code, true
+			}
+		}
+	}
+	return "", false
+}
+
+func normalize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsSymbol(r) && r != '·' {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Trim(s, " .!")
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,48 @@
+package geo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/richardlt/stargazer/crawler/geo"
+)
+
+func TestLookup(t *testing.T) {
+	for location, expected := range map[string]string{
+		"Paris, France":            "FR",
+		"Lyon":                     "FR",
+		"San Francisco, CA":        "US",
+		"SF Bay Area":              "US",
+		"Cambridge, MA":            "US",
+		"Cambridge, MA, USA":       "US",
+		"München":                  "DE",
+		"Berlin, DE":               "DE",
+		"somewhere, DE":            "US",
+		"Springfield, IL":          "US",
+		"Paris, TX":                "US",
+		"Paris, TX, USA":           "US",
+		"Haifa, IL":                "IL",
+		"IL":                       "IL",
+		"Vancouver, Canada":        "CA",
+		"Living in Tokyo 🗼":        "JP",
+		"Bengaluru, Karnataka":     "IN",
+		"São Paulo - Brasil":       "BR",
+		"Remote / United Kingdom":  "GB",
+		"  new   york  ":           "US",
+		"Earth":                    "",
+		"":                         "",
+		"localhost":                "",
+		"Toronto, Ontario, Canada": "CA",
+	} {
+		code, ok := geo.Lookup(location)
+		assert.Equal(t, expected != "", ok, location)
+		assert.Equal(t, expected, code, location)
+	}
+}
+
+func TestCountryName(t *testing.T) {
+	assert.Equal(t, "France", geo.CountryName("FR"))
+	assert.Equal(t, "France", geo.CountryName("fr"))
+	assert.Equal(t, "XX", geo.CountryName("XX"))
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -16,12 +19,17 @@ const ghBaseURL = "https://api.github.com"
 type Client interface {
 	GetRepository(path string) (Repository, error)
 	GetRepositoryConributors(path string) ([]Contributor, error)
-	GetRepositoryStargazer(path string) ([]Stargazer, error)
-	GetRepositoryStargazerPage(path string, page int64) ([]Stargazer, error)
+	IterateRepositoryStargazers(path string, callback func(page []Stargazer) error) error
+	GetRepositoryStargazerPageIfChanged(path string, page int64, etag string) (StargazerPage, error)
+	GetRepositoryTopics(path string) ([]string, error)
+	GetRepositoryReadme(path string) (string, error)
+	HasRepositoryFile(path, file string) (bool, error)
+	GetRepositoryReleases(path string) ([]Release, error)
 	GetUser(login string) (User, error)
 	GetUserOrganizations(login string) ([]Organization, error)
 	ResetRequestCount()
 	GetRequestCount() int64
+	GetRateLimit() RateLimit
 }
 
 var _ Client = new(client)
@@ -33,6 +41,7 @@ func NewClient(token string) Client {
 type client struct {
 	mutex        sync.RWMutex
 	RequestCount int64
+	rateLimit    RateLimit
 	token        string
 }
 
@@ -49,10 +58,28 @@ func (c *client) GetRequestCount() int64 {
 	return v
 }
 
+func (c *client) GetRateLimit() RateLimit {
+	c.mutex.RLock()
+	v := c.rateLimit
+	c.mutex.RUnlock()
+	return v
+}
+
 func (c *client) get(url string, modifiers ...func(req *http.Request)) (json.RawMessage, error) {
+	code, _, buf, err := c.do(url, modifiers...)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("error request at %s with code %d: body=%s", url, code, string(buf)))
+	}
+	return buf, nil
+}
+
+func (c *client) do(url string, modifiers ...func(req *http.Request)) (int, http.Header, []byte, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return 0, nil, nil, errors.WithStack(err)
 	}
 	for i := range modifiers {
 		modifiers[i](req)
@@ -65,46 +92,79 @@ func (c *client) get(url string, modifiers ...func(req *http.Request)) (json.Raw
 	req.Header.Add("Authorization", fmt.Sprintf("token %s", c.token))
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return 0, nil, nil, errors.WithStack(err)
 	}
+	defer res.Body.Close()
+	c.updateRateLimit(res.Header)
 	buf, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return 0, nil, nil, errors.WithStack(err)
 	}
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New(fmt.Sprintf("error request at %s with code %d: body=%s", url, res.StatusCode, string(buf)))
-	}
-	return buf, nil
+	return res.StatusCode, res.Header, buf, nil
 }
 
-func (c *client) getPaginate(url string, modifiers ...func(req *http.Request)) (json.RawMessage, error) {
-	var res []interface{}
+// getPaginate calls given callback with the content of each page, next pages are given by the Link header.
+func (c *client) getPaginate(url string, callback func(buf json.RawMessage) error, modifiers ...func(req *http.Request)) error {
+	next := url + "?per_page=100"
+	for next != "" {
+		logrus.Debugf("getPaginate: load %s\n", next)
 
-	page := 1
-	for {
-		logrus.Debugf("getPaginate: load page %d from %s\n", page, url)
-
-		data, err := c.get(fmt.Sprintf("%s?page=%d&per_page=100", url, page), modifiers...)
+		code, header, buf, err := c.do(next, modifiers...)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if code != http.StatusOK {
+			return errors.New(fmt.Sprintf("error request at %s with code %d: body=%s", next, code, string(buf)))
+		}
+		if err := callback(buf); err != nil {
+			return err
 		}
 
-		var is []interface{}
-		if err := json.Unmarshal(data, &is); err != nil {
-			return nil, errors.WithStack(err)
+		next = parseLinks(header.Get("Link"))["next"]
+	}
+	return nil
+}
+
+// parseLinks returns URLs by relation from a Link header.
+func parseLinks(link string) map[string]string {
+	links := make(map[string]string)
+	for _, part := range strings.Split(link, ",") {
+		fields := strings.Split(part, ";")
+		if len(fields) < 2 {
+			continue
 		}
-		res = append(res, is...)
-		if len(is) < 100 {
-			break
+		url := strings.Trim(strings.TrimSpace(fields[0]), "<>")
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "rel=") {
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimPrefix(param, "rel="), `"`)) {
+					links[rel] = url
+				}
+			}
 		}
-		page++
 	}
+	return links
+}
 
-	buf, err := json.Marshal(res)
-	if err != nil {
-		return nil, errors.WithStack(err)
+// lastPage returns the page number from the last relation of a Link header. Without last nor next relation given page
+// is the last one.
+func lastPage(link string, page int64) int64 {
+	links := parseLinks(link)
+	if last, ok := links["last"]; ok {
+		u, err := neturl.Parse(last)
+		if err != nil {
+			return 0
+		}
+		p, err := strconv.ParseInt(u.Query().Get("page"), 10, 64)
+		if err != nil {
+			return 0
+		}
+		return p
 	}
-	return buf, nil
+	if _, ok := links["next"]; ok {
+		return 0
+	}
+	return page
 }
 
 func (c *client) GetRepository(path string) (Repository, error) {
@@ -131,34 +191,106 @@ func (c *client) GetRepositoryConributors(path string) ([]Contributor, error) {
 	return cs, nil
 }
 
-func (c *client) GetRepositoryStargazer(path string) ([]Stargazer, error) {
-	data, err := c.getPaginate(
+func (c *client) IterateRepositoryStargazers(path string, callback func(page []Stargazer) error) error {
+	return c.getPaginate(
 		fmt.Sprintf("%s/repos/%s/stargazers", ghBaseURL, path),
+		func(buf json.RawMessage) error {
+			var ss []Stargazer
+			if err := json.Unmarshal(buf, &ss); err != nil {
+				return errors.WithStack(err)
+			}
+			return callback(ss)
+		},
 		func(req *http.Request) { req.Header.Add("Accept", "application/vnd.github.v3.star+json") },
 	)
+}
+
+// GetRepositoryStargazerPageIfChanged loads a stargazers page only if its content changed since given ETag was
+// returned. Conditional requests for an unchanged page do not count against the rate limit.
+func (c *client) GetRepositoryStargazerPageIfChanged(path string, page int64, etag string) (StargazerPage, error) {
+	url := fmt.Sprintf("%s/repos/%s/stargazers?page=%d&per_page=100", ghBaseURL, path, page)
+	code, header, buf, err := c.do(url, func(req *http.Request) {
+		req.Header.Add("Accept", "application/vnd.github.v3.star+json")
+		if etag != "" {
+			req.Header.Add("If-None-Match", etag)
+		}
+	})
 	if err != nil {
-		return nil, err
+		return StargazerPage{}, err
 	}
-	var ss []Stargazer
-	if err := json.Unmarshal(data, &ss); err != nil {
-		return nil, err
+	switch code {
+	case http.StatusNotModified:
+		return StargazerPage{ETag: etag, LastPage: lastPage(header.Get("Link"), 0)}, nil
+	case http.StatusOK:
+		p := StargazerPage{ETag: header.Get("ETag"), Changed: true, LastPage: lastPage(header.Get("Link"), page)}
+		if err := json.Unmarshal(buf, &p.Stargazers); err != nil {
+			return StargazerPage{}, errors.WithStack(err)
+		}
+		return p, nil
 	}
-	return ss, nil
+	return StargazerPage{}, errors.New(fmt.Sprintf("error request at %s with code %d: body=%s", url, code, string(buf)))
 }
 
-func (c *client) GetRepositoryStargazerPage(path string, page int64) ([]Stargazer, error) {
+func (c *client) GetRepositoryTopics(path string) ([]string, error) {
 	buf, err := c.get(
-		fmt.Sprintf("%s/repos/%s/stargazers?page=%d&per_page=100", ghBaseURL, path, page),
-		func(req *http.Request) { req.Header.Add("Accept", "application/vnd.github.v3.star+json") },
+		fmt.Sprintf("%s/repos/%s/topics", ghBaseURL, path),
+		func(req *http.Request) { req.Header.Add("Accept", "application/vnd.github.mercy-preview+json") },
 	)
 	if err != nil {
 		return nil, err
 	}
-	var ss []Stargazer
-	if err := json.Unmarshal(buf, &ss); err != nil {
+	var t struct {
+		Names []string `json:"names"`
+	}
+	if err := json.Unmarshal(buf, &t); err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return ss, nil
+	return t.Names, nil
+}
+
+func (c *client) GetRepositoryReadme(path string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/readme", ghBaseURL, path)
+	code, _, buf, err := c.do(url, func(req *http.Request) { req.Header.Add("Accept", "application/vnd.github.v3.raw") })
+	if err != nil {
+		return "", err
+	}
+	switch code {
+	case http.StatusOK:
+		return string(buf), nil
+	case http.StatusNotFound:
+		return "", nil
+	}
+	return "", errors.New(fmt.Sprintf("error request at %s with code %d: body=%s", url, code, string(buf)))
+}
+
+func (c *client) HasRepositoryFile(path, file string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/contents/%s", ghBaseURL, path, file)
+	code, _, buf, err := c.do(url)
+	if err != nil {
+		return false, err
+	}
+	switch code {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, errors.New(fmt.Sprintf("error request at %s with code %d: body=%s", url, code, string(buf)))
+}
+
+func (c *client) GetRepositoryReleases(path string) ([]Release, error) {
+	var rs []Release
+	if err := c.getPaginate(fmt.Sprintf("%s/repos/%s/releases", ghBaseURL, path), func(buf json.RawMessage) error {
+		var page []Release
+		if err := json.Unmarshal(buf, &page); err != nil {
+			return errors.WithStack(err)
+		}
+		rs = append(rs, page...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return rs, nil
 }
 
 func (c *client) GetUser(login string) (User, error) {
@@ -174,13 +306,30 @@ func (c *client) GetUser(login string) (User, error) {
 }
 
 func (c *client) GetUserOrganizations(login string) ([]Organization, error) {
-	data, err := c.getPaginate(fmt.Sprintf("%s/users/%s/orgs", ghBaseURL, login))
-	if err != nil {
-		return nil, err
-	}
 	var os []Organization
-	if err := json.Unmarshal(data, &os); err != nil {
+	if err := c.getPaginate(fmt.Sprintf("%s/users/%s/orgs", ghBaseURL, login), func(buf json.RawMessage) error {
+		var page []Organization
+		if err := json.Unmarshal(buf, &page); err != nil {
+			return errors.WithStack(err)
+		}
+		os = append(os, page...)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	return os, nil
 }
+
+func (c *client) updateRateLimit(h http.Header) {
+	limit, err := strconv.ParseInt(h.Get("X-RateLimit-Limit"), 10, 64)
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.ParseInt(h.Get("X-RateLimit-Remaining"), 10, 64)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	c.rateLimit = RateLimit{Limit: limit, Remaining: remaining}
+	c.mutex.Unlock()
+}
@@ -0,0 +1,61 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseLinks(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"next": "https://api.github.com/repositories/1/stargazers?per_page=100&page=3",
+		"last": "https://api.github.com/repositories/1/stargazers?per_page=100&page=5",
+		"prev": "https://api.github.com/repositories/1/stargazers?per_page=100&page=1",
+	}, parseLinks(`<https://api.github.com/repositories/1/stargazers?per_page=100&page=3>; rel="next", `+
+		`<https://api.github.com/repositories/1/stargazers?per_page=100&page=5>; rel="last", `+
+		`<https://api.github.com/repositories/1/stargazers?per_page=100&page=1>; rel="prev"`))
+	assert.Empty(t, parseLinks(""))
+}
+
+func Test_getPaginate(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/vnd.github.v3.star+json", r.Header.Get("Accept"))
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/stargazers?per_page=100&page=2>; rel="next", <%s/stargazers?per_page=100&page=2>; rel="last"`, srv.URL, srv.URL))
+		}
+		ss := []Stargazer{{}}
+		ss[0].User.Login = "user" + page
+		require.NoError(t, json.NewEncoder(w).Encode(ss))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := &client{}
+	var logins []string
+	require.NoError(t, c.getPaginate(srv.URL+"/stargazers", func(buf json.RawMessage) error {
+		var ss []Stargazer
+		require.NoError(t, json.Unmarshal(buf, &ss))
+		for i := range ss {
+			logins = append(logins, ss[i].User.Login)
+		}
+		return nil
+	}, func(req *http.Request) { req.Header.Add("Accept", "application/vnd.github.v3.star+json") }))
+	assert.Equal(t, []string{"user1", "user2"}, logins)
+}
+
+func Test_lastPage(t *testing.T) {
+	assert.Equal(t, int64(5), lastPage(`<https://api.github.com/repositories/1/stargazers?per_page=100&page=2>; rel="next", <https://api.github.com/repositories/1/stargazers?per_page=100&page=5>; rel="last"`, 1))
+	assert.Equal(t, int64(0), lastPage(`<https://api.github.com/repositories/1/stargazers?per_page=100&page=2>; rel="next"`, 1))
+	assert.Equal(t, int64(5), lastPage(`<https://api.github.com/repositories/1/stargazers?per_page=100&page=4>; rel="prev", <https://api.github.com/repositories/1/stargazers?per_page=100&page=1>; rel="first"`, 5))
+	assert.Equal(t, int64(1), lastPage("", 1))
+}
@@ -3,11 +3,28 @@ package github
 import "time"
 
 type Repository struct {
-	StargazersCount int64 `bson:"stargazers_count" json:"stargazers_count"`
-	Owner           struct {
+	StargazersCount  int64 `bson:"stargazers_count" json:"stargazers_count"`
+	ForksCount       int64 `bson:"forks_count" json:"forks_count"`
+	SubscribersCount int64 `bson:"subscribers_count" json:"subscribers_count"`
+	OpenIssuesCount  int64 `bson:"open_issues_count" json:"open_issues_count"`
+	Size             int64 `bson:"size" json:"size"`
+	Owner            struct {
 		Type string `bson:"type" json:"type"`
 	} `bson:"owner" json:"owner"`
 	FullName string `bson:"full_name" json:"full_name"`
+	// Permissions are the ones of the token's user on the repository
+	Permissions struct {
+		Admin bool `json:"admin"`
+	} `bson:"-" json:"permissions"`
+}
+
+// StargazerPage is a page of stargazers, Changed is false if the page did not change since the given ETag. LastPage is
+// the real count of pages given by the Link header, 0 if unknown.
+type StargazerPage struct {
+	Stargazers []Stargazer
+	ETag       string
+	Changed    bool
+	LastPage   int64
 }
 
 type Contributor struct {
@@ -16,15 +33,48 @@ type Contributor struct {
 
 type Stargazer struct {
 	User struct {
-		Login string `bson:"login" json:"login"`
+		Login     string `bson:"login" json:"login"`
+		AvatarURL string `bson:"avatar_url" json:"avatar_url"`
 	} `bson:"user" json:"user"`
 	StarredAt time.Time `bson:"starred_at" json:"starred_at"`
 }
 
 type User struct {
-	Login string `bson:"login" json:"login"`
+	Login       string    `bson:"login" json:"login"`
+	Type        string    `bson:"type" json:"type"`
+	Name        string    `bson:"name" json:"name"`
+	Company     string    `bson:"company" json:"company"`
+	Location    string    `bson:"location" json:"location"`
+	Bio         string    `bson:"bio" json:"bio"`
+	AvatarURL   string    `bson:"avatar_url" json:"avatar_url"`
+	Followers   int64     `bson:"followers" json:"followers"`
+	PublicRepos int64     `bson:"public_repos" json:"public_repos"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type Release struct {
+	Name        string    `bson:"name" json:"name"`
+	TagName     string    `bson:"tag_name" json:"tag_name"`
+	Draft       bool      `bson:"draft" json:"draft"`
+	Prerelease  bool      `bson:"prerelease" json:"prerelease"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	PublishedAt time.Time `bson:"published_at" json:"published_at"`
 }
 
 type Organization struct {
 	Login string `bson:"login" json:"login"`
 }
+
+type RateLimit struct {
+	Limit     int64
+	Remaining int64
+}
+
+// UsedShare returns the share of the rate limit that was consumed, from 0 to 1.
+func (r RateLimit) UsedShare() float64 {
+	if r.Limit <= 0 {
+		return 0
+	}
+	return float64(r.Limit-r.Remaining) / float64(r.Limit)
+}
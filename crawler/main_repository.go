@@ -1,14 +1,16 @@
 package crawler
 
 import (
-	"time"
-
 	"github.com/sirupsen/logrus"
 
+	"github.com/richardlt/stargazer/config"
 	"github.com/richardlt/stargazer/crawler/github"
 )
 
-func execMainRepositoryRoutine(dbClient *DatabaseClient, ghClient github.Client, repo string, userExpirationDelay int64) error {
+// execMainRepositoryRoutine loads the stargazers of the tenant's main repository and their users.
+func execMainRepositoryRoutine(dbClient *DatabaseClient, ghClient github.Client, tenant config.Tenant, userExpirationDelay int64) error {
+	repo := tenant.MainRepository
+
 	logrus.Infof("execMainRepositoryRoutine: get main repository %s from Github", repo)
 
 	ghRepo, err := ghClient.GetRepository(repo)
@@ -19,7 +21,7 @@ func execMainRepositoryRoutine(dbClient *DatabaseClient, ghClient github.Client,
 	githubStargazersCount := ghRepo.StargazersCount
 
 	logrus.Infof("execMainRepositoryRoutine: get repository %s from database", repo)
-	r, err := dbClient.getRepository(repo)
+	r, err := dbClient.getRepository(tenant.Name, repo)
 	if err != nil {
 		return err
 	}
@@ -28,8 +30,9 @@ func execMainRepositoryRoutine(dbClient *DatabaseClient, ghClient github.Client,
 
 	if !repoExists {
 		r = &repository{
-			Path: repo,
-			Data: ghRepo,
+			Tenant: tenant.Name,
+			Path:   repo,
+			Data:   ghRepo,
 		}
 
 		logrus.Infof("execMainRepositoryRoutine: create repository %s in database", repo)
@@ -56,76 +59,41 @@ func execMainRepositoryRoutine(dbClient *DatabaseClient, ghClient github.Client,
 			}
 		}
 
+		// Stargazers are stored and their users refreshed page by page
 		logrus.Info("execMainRepositoryRoutine: load stargazers from Github")
-		os, err := ghClient.GetRepositoryStargazer(r.Path)
-		if err != nil {
-			return err
-		}
-
-		logrus.Infof("execMainRepositoryRoutine: delete all stargazers for repository %s in database", r.Path)
-		if err := dbClient.deleteStargazers(r.ID); err != nil {
-			return err
-		}
-
-		ss := make([]stargazer, len(os))
-		for i := range os {
-			ss[i].RepositoryID = r.ID
-			ss[i].RepositoryPath = r.Path
-			ss[i].Data = os[i]
-		}
-
-		logrus.Infof("execMainRepositoryRoutine: insert %d stargazers for repository %s in database", len(ss), r.Path)
-		if err := dbClient.insertStargazers(ss); err != nil {
-			return err
-		}
-
-		ss, err = dbClient.getStargazers(repo)
-		if err != nil {
-			return err
-		}
-
-		// Refresh data for all user that starred the main repository
-		logrus.Infof("execMainRepositoryRoutine: iterate over %d stargazers", len(ss))
-		for i := range ss {
-			login := ss[i].Data.User.Login
+		pages := []int64{}
+		if err := ghClient.IterateRepositoryStargazers(r.Path, func(os []github.Stargazer) error {
+			page := int64(len(pages) + 1)
+			pages = append(pages, page)
+
+			ss := make([]stargazer, len(os))
+			for i := range os {
+				ss[i].Tenant = r.Tenant
+				ss[i].RepositoryID = r.ID
+				ss[i].RepositoryPath = r.Path
+				ss[i].Page = page
+				ss[i].Data = os[i]
+			}
 
-			u, err := dbClient.getUser(login)
-			if err != nil {
+			logrus.Infof("execMainRepositoryRoutine: insert %d stargazers from page %d for repository %s in database", len(ss), page, r.Path)
+			if err := dbClient.replaceStargazersPage(r.ID, page, ss); err != nil {
 				return err
 			}
-			needSave := u == nil || (u.Expire.Before(time.Now()) && userExpirationDelay > 0)
-			if needSave {
-				logrus.Infof("execMainRepositoryRoutine: get user %s from Github (%d/%d)", login, i+1, len(ss))
-				o, err := ghClient.GetUser(login)
-				if err != nil {
-					return err
-				}
-				os, err := ghClient.GetUserOrganizations(login)
-				if err != nil {
-					return err
-				}
 
-				expire := time.Now().Add(time.Second * time.Duration(userExpirationDelay))
-				if u == nil {
-					logrus.Infof("execMainRepositoryRoutine: insert user %s in database", login)
-					if err := dbClient.insertUser(&user{
-						Expire:        expire,
-						Login:         login,
-						Data:          o,
-						Organizations: os,
-					}); err != nil {
-						return err
-					}
-				} else {
-					u.Expire = expire
-					u.Data = o
-					u.Organizations = os
-					logrus.Infof("execMainRepositoryRoutine: update user %s in database", login)
-					if err := dbClient.updateUser(u); err != nil {
-						return err
-					}
+			for i := range ss {
+				logrus.Debugf("execMainRepositoryRoutine: refresh user %s (%d/%d on page %d)", ss[i].Data.User.Login, i+1, len(ss), page)
+				if err := refreshUser(dbClient, ghClient, r.Tenant, ss[i].Data.User.Login, userExpirationDelay); err != nil {
+					return err
 				}
 			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		logrus.Infof("execMainRepositoryRoutine: delete stargazers for repository %s from removed pages in database", r.Path)
+		if err := dbClient.deleteStargazersExceptPages(r.ID, pages); err != nil {
+			return err
 		}
 	}
 
@@ -63,34 +63,93 @@ func (mr *MockClientMockRecorder) GetRepositoryConributors(path interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryConributors", reflect.TypeOf((*MockClient)(nil).GetRepositoryConributors), path)
 }
 
-// GetRepositoryStargazer mocks base method
-func (m *MockClient) GetRepositoryStargazer(path string) ([]github.Stargazer, error) {
+// IterateRepositoryStargazers mocks base method
+func (m *MockClient) IterateRepositoryStargazers(path string, callback func([]github.Stargazer) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRepositoryStargazer", path)
-	ret0, _ := ret[0].([]github.Stargazer)
+	ret := m.ctrl.Call(m, "IterateRepositoryStargazers", path, callback)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IterateRepositoryStargazers indicates an expected call of IterateRepositoryStargazers
+func (mr *MockClientMockRecorder) IterateRepositoryStargazers(path, callback interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IterateRepositoryStargazers", reflect.TypeOf((*MockClient)(nil).IterateRepositoryStargazers), path, callback)
+}
+
+// GetRepositoryStargazerPageIfChanged mocks base method
+func (m *MockClient) GetRepositoryStargazerPageIfChanged(path string, page int64, etag string) (github.StargazerPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRepositoryStargazerPageIfChanged", path, page, etag)
+	ret0, _ := ret[0].(github.StargazerPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRepositoryStargazerPageIfChanged indicates an expected call of GetRepositoryStargazerPageIfChanged
+func (mr *MockClientMockRecorder) GetRepositoryStargazerPageIfChanged(path, page, etag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryStargazerPageIfChanged", reflect.TypeOf((*MockClient)(nil).GetRepositoryStargazerPageIfChanged), path, page, etag)
+}
+
+// GetRepositoryTopics mocks base method
+func (m *MockClient) GetRepositoryTopics(path string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRepositoryTopics", path)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRepositoryTopics indicates an expected call of GetRepositoryTopics
+func (mr *MockClientMockRecorder) GetRepositoryTopics(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryTopics", reflect.TypeOf((*MockClient)(nil).GetRepositoryTopics), path)
+}
+
+// GetRepositoryReadme mocks base method
+func (m *MockClient) GetRepositoryReadme(path string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRepositoryReadme", path)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetRepositoryStargazer indicates an expected call of GetRepositoryStargazer
-func (mr *MockClientMockRecorder) GetRepositoryStargazer(path interface{}) *gomock.Call {
+// GetRepositoryReadme indicates an expected call of GetRepositoryReadme
+func (mr *MockClientMockRecorder) GetRepositoryReadme(path interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryStargazer", reflect.TypeOf((*MockClient)(nil).GetRepositoryStargazer), path)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryReadme", reflect.TypeOf((*MockClient)(nil).GetRepositoryReadme), path)
 }
 
-// GetRepositoryStargazerPage mocks base method
-func (m *MockClient) GetRepositoryStargazerPage(path string, page int64) ([]github.Stargazer, error) {
+// HasRepositoryFile mocks base method
+func (m *MockClient) HasRepositoryFile(path, file string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRepositoryStargazerPage", path, page)
-	ret0, _ := ret[0].([]github.Stargazer)
+	ret := m.ctrl.Call(m, "HasRepositoryFile", path, file)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetRepositoryStargazerPage indicates an expected call of GetRepositoryStargazerPage
-func (mr *MockClientMockRecorder) GetRepositoryStargazerPage(path, page interface{}) *gomock.Call {
+// HasRepositoryFile indicates an expected call of HasRepositoryFile
+func (mr *MockClientMockRecorder) HasRepositoryFile(path, file interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryStargazerPage", reflect.TypeOf((*MockClient)(nil).GetRepositoryStargazerPage), path, page)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasRepositoryFile", reflect.TypeOf((*MockClient)(nil).HasRepositoryFile), path, file)
+}
+
+// GetRepositoryReleases mocks base method
+func (m *MockClient) GetRepositoryReleases(path string) ([]github.Release, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRepositoryReleases", path)
+	ret0, _ := ret[0].([]github.Release)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRepositoryReleases indicates an expected call of GetRepositoryReleases
+func (mr *MockClientMockRecorder) GetRepositoryReleases(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositoryReleases", reflect.TypeOf((*MockClient)(nil).GetRepositoryReleases), path)
 }
 
 // GetUser mocks base method
@@ -148,3 +207,17 @@ func (mr *MockClientMockRecorder) GetRequestCount() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequestCount", reflect.TypeOf((*MockClient)(nil).GetRequestCount))
 }
+
+// GetRateLimit mocks base method
+func (m *MockClient) GetRateLimit() github.RateLimit {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRateLimit")
+	ret0, _ := ret[0].(github.RateLimit)
+	return ret0
+}
+
+// GetRateLimit indicates an expected call of GetRateLimit
+func (mr *MockClientMockRecorder) GetRateLimit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimit", reflect.TypeOf((*MockClient)(nil).GetRateLimit))
+}
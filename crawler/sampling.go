@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+// pageBounds is the period covered by a loaded stargazers page.
+type pageBounds struct {
+	First time.Time
+	Last  time.Time
+}
+
+// initialSamplePages returns count pages evenly spread from first to last page.
+func initialSamplePages(expectedPageCount, count int64) []int64 {
+	if count <= 1 || expectedPageCount <= 1 {
+		return []int64{expectedPageCount}
+	}
+	if count > expectedPageCount {
+		count = expectedPageCount
+	}
+	pages := make([]int64, 0, count)
+	for i := int64(0); i < count; i++ {
+		page := 1 + (i*(expectedPageCount-1)+(count-1)/2)/(count-1)
+		if len(pages) == 0 || pages[len(pages)-1] != page {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// nextSamplePage returns the page in the middle of the gap between loaded pages with the widest uncertainty, that is
+// the count of missing stars multiplied by the duration when they could have been starred. A bending curve leaves
+// such wide gaps between the pages loaded on both sides of the bend.
+func nextSamplePage(loaded map[int64]pageBounds) (int64, bool) {
+	pages := make([]int64, 0, len(loaded))
+	for page := range loaded {
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+
+	var next int64
+	var widest float64 = -1
+	for i := 1; i < len(pages); i++ {
+		missing := pages[i] - pages[i-1] - 1
+		if missing <= 0 {
+			continue
+		}
+		duration := loaded[pages[i]].First.Sub(loaded[pages[i-1]].Last).Hours()
+		if duration < 0 {
+			duration = 0
+		}
+		if width := float64(missing) * (duration + 1); width > widest {
+			widest = width
+			next = (pages[i] + pages[i-1]) / 2
+		}
+	}
+	return next, widest >= 0
+}
+
+// interpolateEvolution returns the cumulative count of stars per day from counts per day and page. Stars from pages
+// that were not loaded are spread linearly over the days between loaded pages, for these days confidence bands are
+// the counts if all the missing stars were starred at the end or at the beginning of the gap. Bands are only returned
+// if some pages are missing.
+func interpolateEvolution(msPage []measure, loc *time.Location) ([]database.Measure, []database.Band) {
+	var evolution []database.Measure
+	var bands []database.Band
+	var gaps bool
+
+	previousPage := int64(1)
+	count := int64(0)
+	for i := range msPage {
+		date := msPage[i].Date.In(loc)
+
+		if pageGap := msPage[i].Page - previousPage; pageGap > 1 && len(evolution) > 0 {
+			gaps = true
+			last := evolution[len(evolution)-1]
+			base := (msPage[i].Page - 1) * 100
+			duration := date.Sub(last.Date).Hours()
+			for d := last.Date.AddDate(0, 0, 1); d.Before(date); d = d.AddDate(0, 0, 1) {
+				estimated := last.Count + int64(float64(base-last.Count)*d.Sub(last.Date).Hours()/duration)
+				evolution = append(evolution, database.Measure{Date: d, Count: estimated})
+				bands = append(bands, database.Band{Date: d, Lower: last.Count, Upper: base})
+			}
+		}
+		if pageGap := msPage[i].Page - previousPage; pageGap > 1 {
+			count += (pageGap - 1) * 100
+		}
+		previousPage = msPage[i].Page
+		count += msPage[i].Count
+
+		// Counts from several pages on the same day are merged
+		if len(evolution) > 0 && evolution[len(evolution)-1].Date.Equal(date) {
+			evolution[len(evolution)-1].Count = count
+			bands[len(bands)-1] = database.Band{Date: date, Lower: count, Upper: count}
+			continue
+		}
+		evolution = append(evolution, database.Measure{Date: date, Count: count})
+		bands = append(bands, database.Band{Date: date, Lower: count, Upper: count})
+	}
+
+	if !gaps {
+		return evolution, nil
+	}
+	return evolution, bands
+}
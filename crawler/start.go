@@ -39,11 +39,18 @@ func Start(cfg config.Crawler) error {
 
 	ghClient := github.NewClient(cfg.GHToken)
 
+	checkers, err := NewEligibilityCheckers(mgoClient, ghClient, cfg)
+	if err != nil {
+		return err
+	}
+
 	go func() {
 		logrus.Info("main: start main repository scanner")
 		for {
-			if err := execMainRepositoryRoutine(mgoClient, ghClient, cfg.MainRepository, cfg.UserExpirationDelay); err != nil {
-				logrus.Errorf("%+v", err)
+			for _, t := range cfg.Tenants {
+				if err := execMainRepositoryRoutine(mgoClient, ghClient, t, cfg.UserExpirationDelay); err != nil {
+					logrus.Errorf("%+v", err)
+				}
 			}
 			logrus.Infof("main: main repository scanner routine waiting %ds\n", cfg.MainRepositoryScanDelay)
 			time.Sleep(time.Duration(cfg.MainRepositoryScanDelay) * time.Second)
@@ -53,7 +60,7 @@ func Start(cfg config.Crawler) error {
 	go func() {
 		logrus.Info("main: start task repository scanner")
 		for {
-			if err := execTaskRepositoriesRoutine(pgClient, mgoClient, ghClient, cfg); err != nil {
+			if err := execTaskRepositoriesRoutine(pgClient, mgoClient, ghClient, checkers, cfg); err != nil {
 				logrus.Errorf("%+v", err)
 			}
 			logrus.Infof("main: task repository scanner routine waiting %ds\n", cfg.TaskRepositoryScanDelay)
@@ -61,6 +68,17 @@ func Start(cfg config.Crawler) error {
 		}
 	}()
 
+	go func() {
+		logrus.Info("main: start webhook deliveries")
+		httpClient := webhookHTTPClient(cfg.WebhookAllowLocalAddresses)
+		for {
+			if err := execWebhookDeliveriesRoutine(pgClient, httpClient, cfg); err != nil {
+				logrus.Errorf("%+v", err)
+			}
+			time.Sleep(time.Duration(cfg.WebhookDeliveryScanDelay) * time.Second)
+		}
+	}()
+
 	startDate := time.Now()
 	lastDate := startDate
 	for {
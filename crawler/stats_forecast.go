@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"math"
+	"time"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+const (
+	forecastWindowDays   = 90
+	forecastMinDays      = 14
+	forecastConfidenceZ  = 1.96 // 95% confidence interval
+	forecastMaxDaysAhead = 365 * 10
+	milestoneFirstCount  = 100
+)
+
+// nextMilestone returns the first round milestone (100, 500, 1k, 5k, 10k...) greater than given count.
+func nextMilestone(count int64) int64 {
+	m := int64(milestoneFirstCount)
+	for {
+		if m > count {
+			return m
+		}
+		if m*5 > count {
+			return m * 5
+		}
+		m *= 10
+	}
+}
+
+// computeMilestones returns the dates when the round milestones were reached from a cumulative evolution.
+func computeMilestones(evolution []database.Measure) []database.Milestone {
+	var ms []database.Milestone
+	next := nextMilestone(0)
+	for _, m := range evolution {
+		for m.Count >= next {
+			ms = append(ms, database.Milestone{Count: next, Date: m.Date})
+			next = nextMilestone(next)
+		}
+	}
+	return ms
+}
+
+// computeForecast predicts when the next milestone after the current count of stars will be reached with a linear trend
+// fitted on the cumulative count of stars for recent days. The last day of the series is considered as the current day,
+// days without stars until it are part of the trend. The confidence range comes from the standard error of the fitted
+// slope.
+func computeForecast(daily []database.Measure, count int64) *database.Forecast {
+	if len(daily) < forecastMinDays {
+		return nil
+	}
+
+	var total int64
+	cumulative := make([]float64, len(daily))
+	for i := range daily {
+		total += daily[i].Count
+		cumulative[i] = float64(total)
+	}
+	start := len(daily) - forecastWindowDays
+	if start < 0 {
+		start = 0
+	}
+	ys := cumulative[start:]
+
+	// Least squares fit of y = a + b*x
+	n := float64(len(ys))
+	var sumX, sumY float64
+	for i := range ys {
+		sumX += float64(i)
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var sxx, sxy float64
+	for i := range ys {
+		sxx += (float64(i) - meanX) * (float64(i) - meanX)
+		sxy += (float64(i) - meanX) * (ys[i] - meanY)
+	}
+	slope := sxy / sxx
+	if slope <= 0 {
+		return nil
+	}
+	intercept := meanY - slope*meanX
+	var sse float64
+	for i := range ys {
+		r := ys[i] - (intercept + slope*float64(i))
+		sse += r * r
+	}
+	slopeError := math.Sqrt(sse/(n-2)) / math.Sqrt(sxx)
+
+	milestone := nextMilestone(count)
+	remaining := float64(milestone - count)
+	last := daily[len(daily)-1].Date
+	daysAt := func(rate float64) *time.Time {
+		if rate <= 0 {
+			return nil
+		}
+		days := math.Ceil(remaining / rate)
+		if days > forecastMaxDaysAhead {
+			return nil
+		}
+		d := last.AddDate(0, 0, int(days))
+		return &d
+	}
+
+	date := daysAt(slope)
+	if date == nil {
+		return nil
+	}
+	f := database.Forecast{
+		Milestone:   milestone,
+		Date:        *date,
+		StarsPerDay: math.Round(slope*100) / 100,
+		Latest:      daysAt(slope - forecastConfidenceZ*slopeError),
+	}
+	if earliest := daysAt(slope + forecastConfidenceZ*slopeError); earliest != nil {
+		f.Earliest = *earliest
+	}
+	return &f
+}
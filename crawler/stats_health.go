@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"time"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+// computeHealth keeps the last repository snapshot of each day in given location.
+func computeHealth(ss []repositorySnapshot, loc *time.Location) []database.Health {
+	var res []database.Health
+	for _, s := range ss {
+		h := database.Health{
+			Date:       database.PeriodDay.StartOf(s.Date.In(loc)),
+			Stars:      s.Stars,
+			Forks:      s.Forks,
+			Watchers:   s.Watchers,
+			OpenIssues: s.OpenIssues,
+			Size:       s.Size,
+		}
+		if len(res) > 0 && res[len(res)-1].Date.Equal(h.Date) {
+			res[len(res)-1] = h
+			continue
+		}
+		res = append(res, h)
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+const (
+	qualityNewAccountAge   = 30 * 24 * time.Hour
+	qualityBurstWindow     = time.Minute
+	qualityBurstSize       = 10
+	qualityMaxFlaggedCount = 50
+
+	qualityReasonBot          = "bot"
+	qualityReasonNewAccount   = "new_account"
+	qualityReasonNoActivity   = "no_activity"
+	qualityReasonEmptyProfile = "empty_profile"
+	qualityReasonBurst        = "burst"
+)
+
+// computeQuality returns a stargazers quality score with suspicious accounts.
+// An account is flagged if it is a bot or if at least two suspicious signals are found for it. As accounts are mostly
+// flagged from their profile, the score is the share of not flagged stargazers among those with a known profile.
+func computeQuality(ss []stargazer, us []user) *database.Quality {
+	if len(ss) == 0 {
+		return nil
+	}
+
+	profiles := make(map[string]user, len(us))
+	for i := range us {
+		profiles[strings.ToLower(us[i].Login)] = us[i]
+	}
+	inBurst := detectStarBursts(ss)
+
+	q := database.Quality{Analyzed: int64(len(ss))}
+	var flaggedCount int64
+	for i := range ss {
+		var reasons []string
+		if inBurst[i] {
+			q.Bursts++
+			reasons = append(reasons, qualityReasonBurst)
+		}
+
+		u, ok := profiles[strings.ToLower(ss[i].Data.User.Login)]
+		if ok {
+			q.WithProfile++
+			if u.Data.Type == "Bot" {
+				q.Bots++
+				reasons = append(reasons, qualityReasonBot)
+			}
+			if !u.Data.CreatedAt.IsZero() && ss[i].Data.StarredAt.Sub(u.Data.CreatedAt) < qualityNewAccountAge {
+				q.NewAccounts++
+				reasons = append(reasons, qualityReasonNewAccount)
+			}
+			if u.Data.PublicRepos == 0 && u.Data.Followers == 0 {
+				q.NoActivity++
+				reasons = append(reasons, qualityReasonNoActivity)
+			}
+			if u.Data.Name == "" && u.Data.Company == "" && u.Data.Location == "" && u.Data.Bio == "" {
+				q.EmptyProfiles++
+				reasons = append(reasons, qualityReasonEmptyProfile)
+			}
+		}
+
+		var isBot bool
+		for _, r := range reasons {
+			isBot = isBot || r == qualityReasonBot
+		}
+		if isBot || len(reasons) >= 2 {
+			flaggedCount++
+			if len(q.Flagged) < qualityMaxFlaggedCount {
+				q.Flagged = append(q.Flagged, database.FlaggedStargazer{Name: ss[i].Data.User.Login, Reasons: reasons})
+			}
+		}
+	}
+
+	if q.WithProfile > 0 {
+		score := math.Round((1-float64(flaggedCount)/float64(q.WithProfile))*1000) / 10
+		q.Score = &score
+	}
+	return &q
+}
+
+// detectStarBursts returns the indexes of stargazers that starred in a tight burst with others.
+func detectStarBursts(ss []stargazer) map[int]bool {
+	idx := make([]int, len(ss))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return ss[idx[i]].Data.StarredAt.Before(ss[idx[j]].Data.StarredAt) })
+
+	inBurst := make(map[int]bool)
+	start := 0
+	for end := range idx {
+		for ss[idx[end]].Data.StarredAt.Sub(ss[idx[start]].Data.StarredAt) > qualityBurstWindow {
+			start++
+		}
+		if end-start+1 >= qualityBurstSize {
+			for i := start; i <= end; i++ {
+				inBurst[idx[i]] = true
+			}
+		}
+	}
+	return inBurst
+}
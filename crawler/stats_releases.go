@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"fmt"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+// computeReleases returns the stars gained in the days that followed each release, starting at release day.
+func computeReleases(rs []release, daily []database.Measure, windowDays int64) []database.Release {
+	var res []database.Release
+	for _, r := range rs {
+		if r.Data.Draft || r.Data.PublishedAt.IsZero() {
+			continue
+		}
+
+		name := r.Data.Name
+		if name == "" {
+			name = r.Data.TagName
+		}
+		rel := database.Release{Name: name, TagName: r.Data.TagName, Date: r.Data.PublishedAt}
+		if len(daily) > 0 {
+			rel.Date = r.Data.PublishedAt.In(daily[0].Date.Location())
+		}
+
+		start := database.PeriodDay.StartOf(rel.Date)
+		end := start.AddDate(0, 0, int(windowDays))
+		for _, m := range daily {
+			if !m.Date.Before(start) && m.Date.Before(end) {
+				rel.StarsAfter += m.Count
+			}
+		}
+		res = append(res, rel)
+	}
+	return res
+}
+
+// annotateSpikesWithReleases adds to spike's label the releases published the same day or the day before.
+func annotateSpikesWithReleases(spikes []database.Spike, rs []database.Release) {
+	for i := range spikes {
+		for _, r := range rs {
+			releaseDay := database.PeriodDay.StartOf(r.Date)
+			if !releaseDay.After(spikes[i].Date) && !releaseDay.Before(spikes[i].Date.AddDate(0, 0, -1)) {
+				spikes[i].Label += fmt.Sprintf(" (release %s)", r.Name)
+			}
+		}
+	}
+}
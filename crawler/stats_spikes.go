@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+const (
+	spikeMinWindowDays = 7
+	spikeMinCount      = 5
+)
+
+// computeSpikes returns the days with an unusual count of stars, when the count is greater than the mean plus
+// threshold times the standard deviation of the previous days in the rolling window.
+func computeSpikes(daily []database.Measure, windowDays int64, threshold float64) []database.Spike {
+	var spikes []database.Spike
+	for i := range daily {
+		start := i - int(windowDays)
+		if start < 0 {
+			start = 0
+		}
+		window := daily[start:i]
+		if len(window) < spikeMinWindowDays || daily[i].Count < spikeMinCount {
+			continue
+		}
+
+		var sum float64
+		for _, m := range window {
+			sum += float64(m.Count)
+		}
+		mean := sum / float64(len(window))
+		var variance float64
+		for _, m := range window {
+			variance += (float64(m.Count) - mean) * (float64(m.Count) - mean)
+		}
+		stddev := math.Sqrt(variance / float64(len(window)))
+
+		count := float64(daily[i].Count)
+		if count <= mean+threshold*stddev {
+			continue
+		}
+
+		s := database.Spike{
+			Date:  daily[i].Date,
+			Count: daily[i].Count,
+			Mean:  math.Round(mean*100) / 100,
+		}
+		if stddev > 0 {
+			s.Score = math.Round((count-mean)/stddev*10) / 10
+		}
+		if mean > 0 {
+			s.Label = fmt.Sprintf("%d stars, %.1fx the %d days average", s.Count, count/mean, len(window))
+		} else {
+			s.Label = fmt.Sprintf("%d stars, no stars the previous %d days", s.Count, len(window))
+		}
+		spikes = append(spikes, s)
+	}
+	return spikes
+}
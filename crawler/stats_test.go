@@ -0,0 +1,265 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// dailyFromCounts returns a series of consecutive days that starts at given date.
+func dailyFromCounts(start time.Time, counts ...int64) []database.Measure {
+	ms := make([]database.Measure, len(counts))
+	for i := range counts {
+		ms[i] = database.Measure{Date: start.AddDate(0, 0, i), Count: counts[i]}
+	}
+	return ms
+}
+
+func Test_computeTrend(t *testing.T) {
+	assert.Nil(t, computeTrend(nil))
+
+	// 2021-03-01 is a Monday
+	daily := dailyFromCounts(day(2021, 3, 1),
+		1, 1, 1, 1, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 9,
+	)
+	trend := computeTrend(daily)
+	require.NotNil(t, trend)
+	assert.Equal(t, int64(21), trend.Last7Days)
+	assert.Equal(t, int64(28), trend.Last30Days)
+	assert.Equal(t, int64(28), trend.Last90Days)
+	assert.Equal(t, 3.0, trend.RollingAverage)
+	require.NotNil(t, trend.WeekOverWeek)
+	assert.Equal(t, 200.0, *trend.WeekOverWeek)
+	assert.Equal(t, &database.Measure{Date: day(2021, 3, 14), Count: 9}, trend.BestDay)
+	assert.Equal(t, &database.Measure{Date: day(2021, 3, 8), Count: 21}, trend.BestWeek)
+
+	trend = computeTrend(dailyFromCounts(day(2021, 3, 1), 0, 3))
+	assert.Nil(t, trend.WeekOverWeek)
+
+	// Days without stars since the last star are counted until today
+	evolution := []database.Measure{
+		{Date: day(2021, 3, 1), Count: 5},
+		{Date: day(2021, 3, 2), Count: 8},
+	}
+	daily = dailySeriesUntil(evolution, time.Date(2021, 3, 12, 15, 0, 0, 0, time.UTC))
+	require.Len(t, daily, 12)
+	assert.Equal(t, database.Measure{Date: day(2021, 3, 12), Count: 0}, daily[11])
+	trend = computeTrend(daily)
+	require.NotNil(t, trend)
+	assert.Equal(t, int64(0), trend.Last7Days)
+	assert.Equal(t, int64(8), trend.Last30Days)
+	assert.Equal(t, 0.0, trend.RollingAverage)
+	require.NotNil(t, trend.WeekOverWeek)
+	assert.Equal(t, -100.0, *trend.WeekOverWeek)
+
+	assert.Nil(t, dailySeriesUntil(nil, time.Now()))
+}
+
+func Test_nextMilestone(t *testing.T) {
+	for count, expected := range map[int64]int64{
+		0:      100,
+		99:     100,
+		100:    500,
+		742:    1000,
+		1000:   5000,
+		5000:   10000,
+		12345:  50000,
+		999999: 1000000,
+	} {
+		assert.Equal(t, expected, nextMilestone(count), count)
+	}
+}
+
+func Test_computeMilestones(t *testing.T) {
+	evolution := []database.Measure{
+		{Date: day(2021, 1, 1), Count: 50},
+		{Date: day(2021, 1, 2), Count: 120},
+		{Date: day(2021, 1, 3), Count: 1200},
+	}
+	assert.Equal(t, []database.Milestone{
+		{Count: 100, Date: day(2021, 1, 2)},
+		{Count: 500, Date: day(2021, 1, 3)},
+		{Count: 1000, Date: day(2021, 1, 3)},
+	}, computeMilestones(evolution))
+}
+
+func Test_computeForecast(t *testing.T) {
+	assert.Nil(t, computeForecast(dailyFromCounts(day(2021, 1, 1), 1, 2, 3), 6))
+
+	// 10 stars every day, 300 stars after 30 days
+	counts := make([]int64, 30)
+	for i := range counts {
+		counts[i] = 10
+	}
+	f := computeForecast(dailyFromCounts(day(2021, 1, 1), counts...), 300)
+	require.NotNil(t, f)
+	assert.Equal(t, int64(500), f.Milestone)
+	assert.Equal(t, 10.0, f.StarsPerDay)
+	assert.Equal(t, day(2021, 1, 50), f.Date)
+	assert.Equal(t, day(2021, 1, 50), f.Earliest)
+	require.NotNil(t, f.Latest)
+	assert.Equal(t, day(2021, 1, 50), *f.Latest)
+
+	// Irregular stars give a confidence range around the predicted date
+	for i := range counts {
+		counts[i] = int64(5 + (i%3)*5)
+	}
+	f = computeForecast(dailyFromCounts(day(2021, 1, 1), counts...), 300)
+	require.NotNil(t, f)
+	assert.Equal(t, int64(500), f.Milestone)
+	require.NotNil(t, f.Latest)
+	assert.True(t, f.Earliest.Before(f.Date) || f.Earliest.Equal(f.Date))
+	assert.True(t, f.Latest.After(f.Date) || f.Latest.Equal(f.Date))
+
+	// The milestone comes from the current count of stars, that can be greater than the crawled ones
+	for i := range counts {
+		counts[i] = 10
+	}
+	f = computeForecast(dailyFromCounts(day(2021, 1, 1), counts...), 450)
+	require.NotNil(t, f)
+	assert.Equal(t, int64(500), f.Milestone)
+	assert.Equal(t, day(2021, 1, 35), f.Date)
+
+	// Days without stars until today slow down the trend
+	f = computeForecast(dailyFromCounts(day(2021, 1, 1), append(counts, make([]int64, 30)...)...), 300)
+	require.NotNil(t, f)
+	assert.Equal(t, int64(500), f.Milestone)
+	assert.True(t, f.StarsPerDay < 10)
+	assert.True(t, f.Date.After(day(2021, 1, 80)))
+
+	// No forecast without stars
+	assert.Nil(t, computeForecast(dailyFromCounts(day(2021, 1, 1), make([]int64, 30)...), 0))
+}
+
+func Test_computeSpikes(t *testing.T) {
+	counts := make([]int64, 40)
+	for i := range counts {
+		counts[i] = int64(2 + i%2)
+	}
+	counts[20] = 30
+	counts[35] = 4
+	daily := dailyFromCounts(day(2021, 1, 1), counts...)
+
+	spikes := computeSpikes(daily, 30, 3)
+	require.Len(t, spikes, 1)
+	assert.Equal(t, day(2021, 1, 21), spikes[0].Date)
+	assert.Equal(t, int64(30), spikes[0].Count)
+	assert.Equal(t, 2.5, spikes[0].Mean)
+	assert.Equal(t, 55.0, spikes[0].Score)
+	assert.Equal(t, "30 stars, 12.0x the 20 days average", spikes[0].Label)
+
+	// Days in the first week are never considered as spikes
+	assert.Empty(t, computeSpikes(dailyFromCounts(day(2021, 1, 1), 0, 0, 50), 30, 3))
+}
+
+func Test_computeReleases(t *testing.T) {
+	newRelease := func(name, tag string, publishedAt time.Time, draft bool) release {
+		r := release{}
+		r.Data.Name = name
+		r.Data.TagName = tag
+		r.Data.PublishedAt = publishedAt
+		r.Data.Draft = draft
+		return r
+	}
+	rs := []release{
+		newRelease("First", "v1.0.0", time.Date(2021, 1, 2, 18, 0, 0, 0, time.UTC), false),
+		newRelease("", "v1.1.0", time.Date(2021, 1, 5, 9, 0, 0, 0, time.UTC), false),
+		newRelease("Draft", "v2.0.0", time.Time{}, true),
+	}
+	daily := dailyFromCounts(day(2021, 1, 1), 1, 2, 3, 4, 20, 6)
+
+	releases := computeReleases(rs, daily, 3)
+	assert.Equal(t, []database.Release{
+		{Name: "First", TagName: "v1.0.0", Date: time.Date(2021, 1, 2, 18, 0, 0, 0, time.UTC), StarsAfter: 9},
+		{Name: "v1.1.0", TagName: "v1.1.0", Date: time.Date(2021, 1, 5, 9, 0, 0, 0, time.UTC), StarsAfter: 26},
+	}, releases)
+
+	spikes := []database.Spike{{Date: day(2021, 1, 5), Label: "20 stars"}, {Date: day(2021, 1, 4), Label: "4 stars"}}
+	annotateSpikesWithReleases(spikes, releases)
+	assert.Equal(t, "20 stars (release v1.1.0)", spikes[0].Label)
+	assert.Equal(t, "4 stars", spikes[1].Label)
+}
+
+func Test_computeHealth(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	ss := []repositorySnapshot{
+		{Date: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), Stars: 10, Forks: 1},
+		{Date: time.Date(2021, 1, 1, 23, 30, 0, 0, time.UTC), Stars: 12, Forks: 2},
+		{Date: time.Date(2021, 1, 2, 8, 0, 0, 0, time.UTC), Stars: 13, Forks: 2, OpenIssues: 4},
+	}
+
+	assert.Equal(t, []database.Health{
+		{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Stars: 12, Forks: 2},
+		{Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Stars: 13, Forks: 2, OpenIssues: 4},
+	}, computeHealth(ss, time.UTC))
+
+	health := computeHealth(ss, paris)
+	require.Len(t, health, 2)
+	assert.Equal(t, int64(10), health[0].Stars)
+	assert.Equal(t, int64(13), health[1].Stars)
+	assert.True(t, health[1].Date.Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, paris)))
+}
+
+func Test_initialSamplePages(t *testing.T) {
+	assert.Equal(t, []int64{400}, initialSamplePages(400, 1))
+	assert.Equal(t, []int64{1, 400}, initialSamplePages(400, 2))
+	assert.Equal(t, []int64{1, 101, 201, 300, 400}, initialSamplePages(400, 5))
+	assert.Equal(t, []int64{1, 2, 3}, initialSamplePages(3, 5))
+}
+
+func Test_nextSamplePage(t *testing.T) {
+	_, ok := nextSamplePage(map[int64]pageBounds{1: {}, 2: {}})
+	assert.False(t, ok)
+
+	// Stars from pages 2 to 9 took a year while pages 11 to 19 took a day, the curve bends between 1 and 10
+	page, ok := nextSamplePage(map[int64]pageBounds{
+		1:  {First: day(2020, 1, 1), Last: day(2020, 1, 2)},
+		10: {First: day(2021, 1, 1), Last: day(2021, 1, 1)},
+		20: {First: day(2021, 1, 2), Last: day(2021, 1, 2)},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), page)
+}
+
+func Test_interpolateEvolution(t *testing.T) {
+	evolution, bands := interpolateEvolution([]measure{
+		{Date: day(2021, 1, 1), Page: 1, Count: 60},
+		{Date: day(2021, 1, 2), Page: 1, Count: 40},
+	}, time.UTC)
+	assert.Equal(t, []database.Measure{{Date: day(2021, 1, 1), Count: 60}, {Date: day(2021, 1, 2), Count: 100}}, evolution)
+	assert.Nil(t, bands)
+
+	// Pages 2 and 3 are missing, their 200 stars are spread between January 2 and 6
+	evolution, bands = interpolateEvolution([]measure{
+		{Date: day(2021, 1, 1), Page: 1, Count: 60},
+		{Date: day(2021, 1, 2), Page: 1, Count: 40},
+		{Date: day(2021, 1, 6), Page: 4, Count: 10},
+	}, time.UTC)
+	assert.Equal(t, []database.Measure{
+		{Date: day(2021, 1, 1), Count: 60},
+		{Date: day(2021, 1, 2), Count: 100},
+		{Date: day(2021, 1, 3), Count: 150},
+		{Date: day(2021, 1, 4), Count: 200},
+		{Date: day(2021, 1, 5), Count: 250},
+		{Date: day(2021, 1, 6), Count: 310},
+	}, evolution)
+	assert.Equal(t, []database.Band{
+		{Date: day(2021, 1, 1), Lower: 60, Upper: 60},
+		{Date: day(2021, 1, 2), Lower: 100, Upper: 100},
+		{Date: day(2021, 1, 3), Lower: 100, Upper: 300},
+		{Date: day(2021, 1, 4), Lower: 100, Upper: 300},
+		{Date: day(2021, 1, 5), Lower: 100, Upper: 300},
+		{Date: day(2021, 1, 6), Lower: 310, Upper: 310},
+	}, bands)
+}
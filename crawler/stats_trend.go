@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"math"
+	"time"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+// dailySeriesUntil returns the count of stars per day from a cumulative evolution, with days without stars until given
+// date so the last day of the series is the current day.
+func dailySeriesUntil(evolution []database.Measure, now time.Time) []database.Measure {
+	daily := database.DailySeries(evolution)
+	if len(daily) == 0 {
+		return nil
+	}
+	return database.FillDays(daily, daily[0].Date, now.In(daily[0].Date.Location()))
+}
+
+// computeTrend returns velocity and growth metrics from the count of stars per day.
+// The last day of the series is considered as the current day.
+func computeTrend(daily []database.Measure) *database.Trend {
+	if len(daily) == 0 {
+		return nil
+	}
+
+	sumLast := func(from, days int) int64 {
+		var sum int64
+		for i := len(daily) - 1 - from; i >= 0 && i > len(daily)-1-from-days; i-- {
+			sum += daily[i].Count
+		}
+		return sum
+	}
+
+	t := database.Trend{
+		Last7Days:      sumLast(0, 7),
+		Last30Days:     sumLast(0, 30),
+		Last90Days:     sumLast(0, 90),
+		RollingAverage: math.Round(float64(sumLast(0, 7))/7*100) / 100,
+	}
+
+	if previous := sumLast(7, 7); previous > 0 {
+		growth := math.Round(float64(t.Last7Days-previous)/float64(previous)*1000) / 10
+		t.WeekOverWeek = &growth
+	}
+
+	for _, m := range daily {
+		if t.BestDay == nil || m.Count > t.BestDay.Count {
+			best := m
+			t.BestDay = &best
+		}
+	}
+	for _, m := range database.AggregateSeries(daily, database.PeriodWeek) {
+		if t.BestWeek == nil || m.Count > t.BestWeek.Count {
+			best := m
+			t.BestWeek = &best
+		}
+	}
+
+	return &t
+}
@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/richardlt/stargazer/crawler/geo"
+	"github.com/richardlt/stargazer/database"
+)
+
+const statsTopCount = 10
+
+var companySuffixes = []string{
+	"incorporated", "inc", "llc", "ltd", "limited", "gmbh", "corporation", "corp",
+	"co", "company", "sa", "sas", "sarl", "ag", "bv", "plc", "pty", "srl", "oy", "ab",
+}
+
+// normalizeCompany returns a key and a display name for the free-form company field of a user profile,
+// so that "@acme", "Acme Inc." and "ACME" are counted as the same company.
+func normalizeCompany(company string) (string, string) {
+	company = strings.TrimSpace(company)
+	// Only keep the first company if several are given
+	if i := strings.IndexAny(company, ",|/&"); i > 0 {
+		company = company[:i]
+	}
+	company = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(company), "@"))
+	if i := strings.Index(company, " @"); i > 0 {
+		company = company[:i]
+	}
+
+	words := strings.Fields(company)
+	for len(words) > 1 {
+		last := strings.ToLower(strings.Trim(words[len(words)-1], ".,()"))
+		last = strings.ReplaceAll(last, ".", "")
+		var isSuffix bool
+		for _, s := range companySuffixes {
+			if last == s {
+				isSuffix = true
+				break
+			}
+		}
+		if !isSuffix {
+			break
+		}
+		words = words[:len(words)-1]
+	}
+	label := strings.Trim(strings.Join(words, " "), " .,-")
+	return strings.ToLower(label), label
+}
+
+func computeTopCompanies(us []user) []database.Breakdown {
+	counts := make(map[string]int64)
+	labels := make(map[string]string)
+	for i := range us {
+		key, label := normalizeCompany(us[i].Data.Company)
+		if key == "" {
+			continue
+		}
+		counts[key]++
+		if _, ok := labels[key]; !ok {
+			labels[key] = label
+		}
+	}
+	return topBreakdown(counts, labels, statsTopCount)
+}
+
+func computeTopOrganizations(us []user) []database.Breakdown {
+	counts := make(map[string]int64)
+	labels := make(map[string]string)
+	for i := range us {
+		seen := make(map[string]bool)
+		for _, o := range us[i].Organizations {
+			key := strings.ToLower(o.Login)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			counts[key]++
+			if _, ok := labels[key]; !ok {
+				labels[key] = o.Login
+			}
+		}
+	}
+	return topBreakdown(counts, labels, statsTopCount)
+}
+
+func computeCountries(us []user) []database.Country {
+	counts := make(map[string]int64)
+	var total int64
+	for i := range us {
+		code, ok := geo.Lookup(us[i].Data.Location)
+		if !ok {
+			continue
+		}
+		counts[code]++
+		total++
+	}
+
+	cs := make([]database.Country, 0, len(counts))
+	for code, count := range counts {
+		cs = append(cs, database.Country{
+			Code:  code,
+			Name:  geo.CountryName(code),
+			Count: count,
+			Share: math.Round(float64(count)/float64(total)*1000) / 10,
+		})
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Count != cs[j].Count {
+			return cs[i].Count > cs[j].Count
+		}
+		return cs[i].Code < cs[j].Code
+	})
+	return cs
+}
+
+func topBreakdown(counts map[string]int64, labels map[string]string, limit int) []database.Breakdown {
+	bs := make([]database.Breakdown, 0, len(counts))
+	for key, count := range counts {
+		bs = append(bs, database.Breakdown{Name: labels[key], Count: count})
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		if bs[i].Count != bs[j].Count {
+			return bs[i].Count > bs[j].Count
+		}
+		return strings.ToLower(bs[i].Name) < strings.ToLower(bs[j].Name)
+	})
+	if len(bs) > limit {
+		bs = bs[:limit]
+	}
+	return bs
+}
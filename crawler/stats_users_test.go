@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/richardlt/stargazer/crawler/github"
+	"github.com/richardlt/stargazer/database"
+)
+
+func Test_normalizeCompany(t *testing.T) {
+	for _, c := range []struct {
+		company string
+		key     string
+		label   string
+	}{
+		{"@acme", "acme", "acme"},
+		{"Acme Inc.", "acme", "Acme"},
+		{" ACME, Inc ", "acme", "ACME"},
+		{"Acme Corp", "acme", "Acme"},
+		{"@acme @other", "acme", "acme"},
+		{"Foo Bar GmbH", "foo bar", "Foo Bar"},
+		{"Inc", "inc", "Inc"},
+		{"@github | @microsoft", "github", "github"},
+		{"", "", ""},
+	} {
+		key, label := normalizeCompany(c.company)
+		assert.Equal(t, c.key, key, c.company)
+		assert.Equal(t, c.label, label, c.company)
+	}
+}
+
+func Test_computeTopCompanies(t *testing.T) {
+	newUser := func(company string, orgs ...string) user {
+		u := user{Data: github.User{Company: company}}
+		for _, o := range orgs {
+			u.Organizations = append(u.Organizations, github.Organization{Login: o})
+		}
+		return u
+	}
+	us := []user{
+		newUser("@acme", "acme", "golang"),
+		newUser("Acme Inc.", "acme"),
+		newUser("Other"),
+		newUser("", "golang", "Golang"),
+	}
+
+	assert.Equal(t, []database.Breakdown{{Name: "acme", Count: 2}, {Name: "Other", Count: 1}}, computeTopCompanies(us))
+	assert.Equal(t, []database.Breakdown{{Name: "acme", Count: 2}, {Name: "golang", Count: 2}}, computeTopOrganizations(us))
+}
+
+func Test_computeCountries(t *testing.T) {
+	us := []user{
+		{Data: github.User{Location: "Paris, France"}},
+		{Data: github.User{Location: "Lyon"}},
+		{Data: github.User{Location: "Berlin"}},
+		{Data: github.User{Location: "The Internet"}},
+	}
+
+	assert.Equal(t, []database.Country{
+		{Code: "FR", Name: "France", Count: 2, Share: 66.7},
+		{Code: "DE", Name: "Germany", Count: 1, Share: 33.3},
+	}, computeCountries(us))
+}
+
+func Test_computeQuality(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	newStargazer := func(login string, starredAt time.Time) stargazer {
+		s := stargazer{}
+		s.Data.User.Login = login
+		s.Data.StarredAt = starredAt
+		return s
+	}
+
+	var ss []stargazer
+	// 10 stars in less than a minute
+	for i := 0; i < 10; i++ {
+		ss = append(ss, newStargazer(fmt.Sprintf("burst%d", i), now.Add(time.Duration(i)*5*time.Second)))
+	}
+	ss = append(ss,
+		newStargazer("legit", now.Add(-48*time.Hour)),
+		newStargazer("fresh", now.Add(-24*time.Hour)),
+		newStargazer("robot", now.Add(-72*time.Hour)),
+	)
+	us := []user{
+		{Login: "burst0", Data: github.User{CreatedAt: now.Add(-24 * time.Hour), PublicRepos: 3}},
+		{Login: "burst1", Data: github.User{CreatedAt: now.Add(-365 * 24 * time.Hour), PublicRepos: 3, Name: "Burst"}},
+		{Login: "legit", Data: github.User{CreatedAt: now.Add(-365 * 24 * time.Hour), PublicRepos: 12, Followers: 4, Name: "Legit"}},
+		{Login: "fresh", Data: github.User{CreatedAt: now.Add(-25 * time.Hour)}},
+		{Login: "robot", Data: github.User{Type: "Bot", CreatedAt: now.Add(-365 * 24 * time.Hour), Name: "Robot"}},
+	}
+
+	q := computeQuality(ss, us)
+	require.NotNil(t, q)
+	assert.Equal(t, int64(13), q.Analyzed)
+	assert.Equal(t, int64(5), q.WithProfile)
+	assert.Equal(t, int64(10), q.Bursts)
+	assert.Equal(t, int64(1), q.Bots)
+	assert.Equal(t, int64(2), q.NewAccounts)
+	assert.Equal(t, int64(2), q.NoActivity)
+	assert.Equal(t, int64(2), q.EmptyProfiles)
+	assert.Equal(t, []database.FlaggedStargazer{
+		{Name: "burst0", Reasons: []string{"burst", "new_account", "empty_profile"}},
+		{Name: "fresh", Reasons: []string{"new_account", "no_activity", "empty_profile"}},
+		{Name: "robot", Reasons: []string{"bot", "no_activity"}},
+	}, q.Flagged)
+	require.NotNil(t, q.Score)
+	assert.Equal(t, 40.0, *q.Score)
+
+	// Without known profiles there is no score
+	q = computeQuality(ss, nil)
+	require.NotNil(t, q)
+	assert.Equal(t, int64(10), q.Bursts)
+	assert.Nil(t, q.Score)
+
+	assert.Nil(t, computeQuality(nil, nil))
+}
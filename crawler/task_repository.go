@@ -12,17 +12,23 @@ import (
 	"github.com/richardlt/stargazer/database"
 )
 
-func execTaskRepositoriesRoutine(pgClient *database.DB, mgoClient *DatabaseClient, ghClient github.Client, cfg config.Crawler) error {
-	es, err := pgClient.GetAllWithStatus(database.StatusRequested)
+func execTaskRepositoriesRoutine(pgClient *database.DB, mgoClient *DatabaseClient, ghClient github.Client, checkers map[string]EligibilityChecker, cfg config.Crawler) error {
+	// Entries still processing were interrupted during a previous run
+	es, err := pgClient.GetAllWithStatus(database.StatusRequested, database.StatusProcessing)
 	if err != nil {
 		return err
 	}
 
 	for _, e := range es {
-		invalid, err := CheckTaskRepositoryRoutine(pgClient, mgoClient, ghClient, cfg, e)
+		e.Status = database.StatusProcessing
+		if err := pgClient.Update(&e); err != nil {
+			return err
+		}
+
+		invalid, err := CheckTaskRepositoryRoutine(pgClient, mgoClient, ghClient, checkers, cfg, e)
 		if invalid {
-			logrus.Infof("execTaskRepositoriesRoutine: delete entry for %s: %v", e.Repository, err)
-			return pgClient.Delete(e.Repository)
+			logrus.Infof("execTaskRepositoriesRoutine: delete entry for %s on tenant %s: %v", e.Repository, e.Tenant, err)
+			return pgClient.Delete(e.Tenant, e.Repository)
 		} else if err != nil {
 			return err
 		}
@@ -32,7 +38,17 @@ func execTaskRepositoriesRoutine(pgClient *database.DB, mgoClient *DatabaseClien
 			return err
 		}
 
-		if err := ComputeTaskRepositoryRoutine(pgClient, mgoClient, e); err != nil {
+		if err := LoadReleasesForRepo(mgoClient, ghClient, e); err != nil {
+			return err
+		}
+
+		if cfg.TaskRepositoryEnrichUsers {
+			if err := EnrichStargazerUsersForRepo(mgoClient, ghClient, cfg, e); err != nil {
+				return err
+			}
+		}
+
+		if err := ComputeTaskRepositoryRoutine(pgClient, mgoClient, cfg, e); err != nil {
 			return err
 		}
 	}
@@ -40,7 +56,7 @@ func execTaskRepositoriesRoutine(pgClient *database.DB, mgoClient *DatabaseClien
 	return nil
 }
 
-func CheckTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClient, ghClient github.Client, cfg config.Crawler, e database.Entry) (bool, error) {
+func CheckTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClient, ghClient github.Client, checkers map[string]EligibilityChecker, cfg config.Crawler, e database.Entry) (bool, error) {
 	logrus.Infof("execTaskRepositoryRoutine: starting scan for repository %s", e.Repository)
 
 	// Check that repository path is valid
@@ -48,67 +64,72 @@ func CheckTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClient
 	if len(rs) != 2 {
 		return true, errors.Errorf("invalid repository path %s", e.Repository)
 	}
-	owner := rs[0]
 
-	// Check if repository was not excluded
-	var excluded bool
-	for i := range cfg.TaskRepositoryExclusions {
-		if strings.ToLower(cfg.TaskRepositoryExclusions[i]) == strings.ToLower(e.Repository) {
-			excluded = true
-			break
-		}
+	// Check that the entry's tenant exists
+	tenant, ok := cfg.Tenant(e.Tenant)
+	checker, okChecker := checkers[e.Tenant]
+	if !ok || !okChecker {
+		return true, errors.Errorf("unknown tenant %s for repository %s", e.Tenant, e.Repository)
 	}
-	if excluded {
+
+	// Check if repository was not excluded
+	if tenant.IsExcluded(e.Repository) {
 		return true, errors.Errorf("exluded repository %s", e.Repository)
 	}
 
-	// Check that the repository owner starred the main repository
-	// For organization repository, first check that one stargazer of the main repository is in the organization
-	exists, err := mgoClient.existsOneOfRepositoryStargazer(cfg.MainRepository, owner)
+	// Load the repository from GH only once, checkers that don't need it can reject the repository without API call
+	var ghRepo github.Repository
+	var errLoad error
+	var loaded bool
+	loadRepository := func() (github.Repository, error) {
+		if !loaded {
+			ghRepo, errLoad = ghClient.GetRepository(e.Repository)
+			loaded = true
+		}
+		return ghRepo, errLoad
+	}
+
+	// Check that the repository matches one of the eligibility strategies (ex: owner starred the main repository)
+	eligible, err := checker.IsEligible(e.Repository, loadRepository)
+	if errLoad != nil {
+		return true, errors.Errorf("repository not found on GH %s", e.Repository)
+	}
 	if err != nil {
 		return false, err
 	}
-	if !exists {
-		return true, errors.Errorf("no stargazer found on main repo for %s", e.Repository)
+	if !eligible {
+		return true, errors.Errorf("repository %s is not eligible for stats computing", e.Repository)
 	}
 
-	// Load the repository from GH
-	ghRepo, err := ghClient.GetRepository(e.Repository)
-	if err != nil {
+	if _, err := loadRepository(); err != nil {
 		return true, errors.Errorf("repository not found on GH %s", e.Repository)
 	}
 
-	if ghRepo.Owner.Type == "Organization" {
-		logrus.Debugf("execTaskRepositoryRoutine: repository owner is an organization, checking contributors for %s", e.Repository)
-		contributors, err := ghClient.GetRepositoryConributors(ghRepo.FullName)
-		if err != nil || len(contributors) == 0 {
-			return true, errors.Errorf("repository contributors not found on GH %s", e.Repository)
-		}
-		logins := make([]string, len(contributors))
-		for i := 0; i < int(cfg.TaskRepositoryOrgContributorsToCheck) && i < len(contributors); i++ {
-			logins[i] = contributors[i].Login
-		}
-
-		// For organization repository we check that one of the top contributors starred the main repository
-		exists, err := mgoClient.existsOneOfRepositoryStargazer(cfg.MainRepository, logins...)
-		if err != nil {
-			return false, err
-		}
-		if !exists {
-			return true, errors.Errorf("no contributors stargazer found on main repo for %s", e.Repository)
-		}
+	logrus.Debugf("stargazer routine: insert snapshot for repository %s in database", e.Repository)
+	if err := mgoClient.insertRepositorySnapshot(&repositorySnapshot{
+		Tenant:         e.Tenant,
+		RepositoryPath: e.Repository,
+		Date:           time.Now(),
+		Stars:          ghRepo.StargazersCount,
+		Forks:          ghRepo.ForksCount,
+		Watchers:       ghRepo.SubscribersCount,
+		OpenIssues:     ghRepo.OpenIssuesCount,
+		Size:           ghRepo.Size,
+	}); err != nil {
+		return false, err
 	}
 
 	logrus.Debugf("stargazer routine: get repository %s from database", e.Repository)
-	r, err := mgoClient.getRepository(e.Repository)
+	r, err := mgoClient.getRepository(e.Tenant, e.Repository)
 	if err != nil {
 		return false, err
 	}
 	if r == nil {
 		logrus.Debugf("stargazer routine: create repository %s in database", e.Repository)
 		return false, mgoClient.insertRepository(&repository{
-			Path: e.Repository,
-			Data: ghRepo,
+			Tenant: e.Tenant,
+			Path:   e.Repository,
+			Data:   ghRepo,
 		})
 	}
 	logrus.Debugf("stargazer routine: update repository %s in database", e.Repository)
@@ -117,123 +138,301 @@ func CheckTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClient
 }
 
 func LoadStargazerForRepo(mgoClient *DatabaseClient, ghClient github.Client, cfg config.Crawler, e database.Entry) error {
-	r, err := mgoClient.getRepository(e.Repository)
+	r, err := mgoClient.getRepository(e.Tenant, e.Repository)
 	if err != nil {
 		return err
 	}
 
-	expectedPageCount := int64((r.Data.StargazersCount / 100) + 1)
-	if expectedPageCount > 400 { // GH limit on page count is 400
-		expectedPageCount = 400
+	// Resume the last crawl session if it was not completed, pages unchanged since the last completed one are not loaded again
+	session, err := mgoClient.getLastCrawlSession(r.Tenant, r.Path, false)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.CompletedAt != nil {
+		session = &crawlSession{Tenant: r.Tenant, RepositoryID: r.ID, RepositoryPath: r.Path, StartedAt: time.Now()}
+		if err := mgoClient.insertCrawlSession(session); err != nil {
+			return err
+		}
+	} else {
+		logrus.Infof("stargazer routine: resume crawl session started at %s for repo %s with %d pages loaded", session.StartedAt, r.Path, len(session.Pages))
+	}
+	previous := make(map[int64]crawlPage)
+	if last, err := mgoClient.getLastCrawlSession(r.Tenant, r.Path, true); err != nil {
+		return err
+	} else if last != nil {
+		for _, p := range last.Pages {
+			previous[p.Page] = p
+		}
 	}
-	logrus.Infof("stargazer routine: load stargazers for repo %s from Github from %d pages expected", r.Path, expectedPageCount)
-	getPage := func(path string, page, expectedPageCount int64) ([]stargazer, error) {
+
+	// The page count is given by Github when loading a page, it is guessed from the stars count until then
+	lastPage := int64((r.Data.StargazersCount / 100) + 1)
+	setLastPage := func(page int64) {
+		if page > 400 { // GH limit on page count is 400
+			page = 400
+		}
+		if page > 0 {
+			lastPage = page
+		}
+	}
+
+	loaded := make(map[int64]pageBounds, cfg.TaskRepositoryMaxStargazerPages)
+	for _, p := range session.Pages {
+		loaded[p.Page] = pageBounds{First: p.First, Last: p.Last}
+		setLastPage(p.LastPage)
+	}
+
+	load := func(page int64) error {
+		if _, ok := loaded[page]; ok {
+			return nil
+		}
+
+		etag := previous[page].ETag
+		if etag != "" {
+			count, err := mgoClient.countStargazersPage(r.ID, page)
+			if err != nil {
+				return err
+			}
+			if count != previous[page].Count {
+				etag = ""
+			}
+		}
+
 		logrus.Infof("stargazer routine: load stargazers page %d for repo %s from Github", page, r.Path)
-		os, err := ghClient.GetRepositoryStargazerPage(r.Path, page)
+		res, err := ghClient.GetRepositoryStargazerPageIfChanged(r.Path, page, etag)
 		if err != nil {
-			return nil, err
-		}
-		ss := make([]stargazer, len(os))
-		for i := range os {
-			ss[i].RepositoryID = r.ID
-			ss[i].RepositoryPath = r.Path
-			ss[i].Page = page
-			ss[i].LastPage = page == expectedPageCount
-			ss[i].Data = os[i]
-		}
-		return ss, nil
-	}
-
-	ss := make([]stargazer, 0, cfg.TaskRepositoryMaxStargazerPages*100)
-
-	if expectedPageCount > cfg.TaskRepositoryMaxStargazerPages && r.Path != cfg.MainRepository {
-		var page int64
-		for i := cfg.TaskRepositoryMaxStargazerPages; i > 0; i-- {
-			if i == cfg.TaskRepositoryMaxStargazerPages {
-				page = expectedPageCount
-			} else if i > 1 {
-				page -= int64(float64(page) / float64(i))
-			} else {
-				page = 1
+			return err
+		}
+		setLastPage(res.LastPage)
+
+		p := previous[page]
+		if res.Changed {
+			ss := make([]stargazer, len(res.Stargazers))
+			for i := range res.Stargazers {
+				ss[i].Tenant = r.Tenant
+				ss[i].RepositoryID = r.ID
+				ss[i].RepositoryPath = r.Path
+				ss[i].Page = page
+				ss[i].LastPage = page == lastPage
+				ss[i].Data = res.Stargazers[i]
 			}
-			os, err := getPage(r.Path, page, expectedPageCount)
-			if err != nil {
+			if err := mgoClient.replaceStargazersPage(r.ID, page, ss); err != nil {
+				return err
+			}
+			p = crawlPage{Page: page, ETag: res.ETag, Count: int64(len(ss)), LastPage: res.LastPage}
+			if len(ss) > 0 {
+				p.First, p.Last = ss[0].Data.StarredAt, ss[len(ss)-1].Data.StarredAt
+			}
+		} else {
+			logrus.Debugf("stargazer routine: page %d for repo %s did not change", page, r.Path)
+			if res.LastPage > 0 {
+				p.LastPage = res.LastPage
+			}
+			// Not modified responses have no Link header, the page count is the one known from the previous crawl
+			setLastPage(p.LastPage)
+		}
+
+		if err := mgoClient.addCrawlSessionPage(session.ID, p); err != nil {
+			return err
+		}
+		loaded[page] = pageBounds{First: p.First, Last: p.Last}
+		return nil
+	}
+
+	// Load the first page to know the real page count
+	if err := load(1); err != nil {
+		return err
+	}
+	logrus.Infof("stargazer routine: load stargazers for repo %s from Github from %d pages", r.Path, lastPage)
+
+	// Last stargazers can be on the last two pages, they are always loaded so sampling leaves room for them
+	missingLastPages := func() int64 {
+		var count int64
+		for _, page := range []int64{lastPage - 1, lastPage} {
+			if _, ok := loaded[page]; page >= 1 && !ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	if lastPage > cfg.TaskRepositoryMaxStargazerPages && !cfg.IsMainRepository(r.Path) {
+		// Half of the pages are evenly spread, others are loaded where the curve bends most
+		for _, page := range initialSamplePages(lastPage, (cfg.TaskRepositoryMaxStargazerPages+1)/2) {
+			if err := load(page); err != nil {
+				return err
+			}
+		}
+		for int64(len(loaded))+missingLastPages() < cfg.TaskRepositoryMaxStargazerPages {
+			page, ok := nextSamplePage(loaded)
+			if !ok {
+				break
+			}
+			if err := load(page); err != nil {
 				return err
 			}
-			ss = append(ss, os...)
 		}
 	} else {
-		for i := int64(1); i <= expectedPageCount; i++ {
-			os, err := getPage(r.Path, i, expectedPageCount)
-			if err != nil {
+		for i := int64(1); i <= lastPage; i++ {
+			if err := load(i); err != nil {
 				return err
 			}
-			ss = append(ss, os...)
 		}
 	}
 
-	logrus.Infof("stargazer routine: delete all stargazers for repository %s in database", r.Path)
-	if err := mgoClient.deleteStargazers(r.ID); err != nil {
+	// The page count can change while crawling, pages already loaded are not loaded again
+	for {
+		current := lastPage
+		for _, page := range []int64{current - 1, current} {
+			if page < 1 {
+				continue
+			}
+			if err := load(page); err != nil {
+				return err
+			}
+		}
+		if current == lastPage {
+			break
+		}
+	}
+
+	pages := make([]int64, 0, len(loaded))
+	for page := range loaded {
+		pages = append(pages, page)
+	}
+	logrus.Infof("stargazer routine: delete stargazers for repository %s from pages not loaded in database", r.Path)
+	if err := mgoClient.deleteStargazersExceptPages(r.ID, pages); err != nil {
 		return err
 	}
+	if err := mgoClient.updateStargazersLastPage(r.ID, lastPage); err != nil {
+		return err
+	}
+
+	return mgoClient.completeCrawlSession(session)
+}
 
-	logrus.Infof("stargazer routine: insert %d stargazers for repository %s in database", len(ss), r.Path)
-	if err := mgoClient.insertStargazers(ss); err != nil {
+func LoadReleasesForRepo(mgoClient *DatabaseClient, ghClient github.Client, e database.Entry) error {
+	r, err := mgoClient.getRepository(e.Tenant, e.Repository)
+	if err != nil {
 		return err
 	}
 
+	logrus.Infof("stargazer routine: load releases for repo %s from Github", r.Path)
+	os, err := ghClient.GetRepositoryReleases(r.Path)
+	if err != nil {
+		return err
+	}
+
+	rs := make([]release, len(os))
+	for i := range os {
+		rs[i].Tenant = r.Tenant
+		rs[i].RepositoryID = r.ID
+		rs[i].RepositoryPath = r.Path
+		rs[i].Data = os[i]
+	}
+
+	logrus.Infof("stargazer routine: delete all releases for repository %s in database", r.Path)
+	if err := mgoClient.deleteReleases(r.ID); err != nil {
+		return err
+	}
+
+	logrus.Infof("stargazer routine: insert %d releases for repository %s in database", len(rs), r.Path)
+	return mgoClient.insertReleases(rs)
+}
+
+// EnrichStargazerUsersForRepo loads user profiles for the recent stargazers of the repository.
+// It stops once the configured share of the Github rate limit was used.
+func EnrichStargazerUsersForRepo(mgoClient *DatabaseClient, ghClient github.Client, cfg config.Crawler, e database.Entry) error {
+	ss, err := mgoClient.getLastStargazers(e.Tenant, e.Repository, cfg.TaskRepositoryEnrichUsersCount)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("stargazer routine: load users for %d stargazers of repo %s", len(ss), e.Repository)
+	for i := range ss {
+		rateLimit := ghClient.GetRateLimit()
+		if rateLimit.UsedShare() >= cfg.TaskRepositoryEnrichUsersShare {
+			logrus.Infof("stargazer routine: stop loading users for repo %s, %d/%d of rate limit used", e.Repository, rateLimit.Limit-rateLimit.Remaining, rateLimit.Limit)
+			break
+		}
+		if err := refreshUser(mgoClient, ghClient, e.Tenant, ss[i].Data.User.Login, cfg.UserExpirationDelay); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func ComputeTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClient, e database.Entry) error {
+func ComputeTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClient, cfg config.Crawler, e database.Entry) error {
 	logrus.Debugf("execTaskRepositoryRoutine: starting compute stats for repo for %s", e.Repository)
-	r, err := mgoClient.getRepository(e.Repository)
+	previous := e
+
+	r, err := mgoClient.getRepository(e.Tenant, e.Repository)
 	if err != nil {
 		return err
 	}
 
 	e.Stats.CountStars = r.Data.StargazersCount
 
+	// Stats per days are computed in the deployment's timezone
+	loc := cfg.Location()
+	e.Stats.Timezone = loc.String()
+
 	// Compute evolution stats
-	msPage, err := mgoClient.getRepoStarCountPerDaysAndPage(r.Path)
+	msPage, err := mgoClient.getRepoStarCountPerDaysAndPage(r.Tenant, r.Path, e.Stats.Timezone)
 	if err != nil {
 		return err
 	}
-	e.Stats.Evolution = nil
+	e.Stats.Evolution, e.Stats.EvolutionBands = interpolateEvolution(msPage, loc)
+	crawled := e.Stats.Evolution
 	if len(msPage) > 0 {
-		previousPage := int64(1)
-		count := int64(0)
-		for i := range msPage {
-			if previousPage < msPage[i].Page {
-				pageGap := msPage[i].Page - previousPage
-				if pageGap > 1 {
-					count += (pageGap - 1) * 100
-				}
-			}
-			previousPage = msPage[i].Page
-			count += msPage[i].Count
-			e.Stats.Evolution = append(e.Stats.Evolution, database.Measure{Date: msPage[i].Date, Count: count})
+		now := time.Now().In(loc)
+		e.Stats.Evolution = append(e.Stats.Evolution, database.Measure{Date: now, Count: e.Stats.CountStars})
+		if e.Stats.EvolutionBands != nil {
+			e.Stats.EvolutionBands = append(e.Stats.EvolutionBands, database.Band{Date: now, Lower: e.Stats.CountStars, Upper: e.Stats.CountStars})
 		}
-		e.Stats.Evolution = append(e.Stats.Evolution, database.Measure{Date: time.Now(), Count: e.Stats.CountStars})
 	}
 
-	// Compute count per days stats
-	ms, err := mgoClient.getRepoStarCountPerDays(r.Path)
+	// Compute count per weeks and months, trend, forecast and spikes for the whole history. The last evolution measure
+	// is left out as it gives the stars that were not crawled for repositories with too many stars. Days without stars
+	// are counted until today. Milestones and forecast start from the current count of stars.
+	daily := dailySeriesUntil(crawled, time.Now().In(loc))
+	e.Stats.PerWeeks = database.AggregateSeries(daily, database.PeriodWeek)
+	e.Stats.PerMonths = database.AggregateSeries(daily, database.PeriodMonth)
+	e.Stats.Trend = computeTrend(daily)
+	e.Stats.Milestones = computeMilestones(e.Stats.Evolution)
+	e.Stats.Forecast = computeForecast(daily, e.Stats.CountStars)
+	e.Stats.Spikes = computeSpikes(daily, cfg.StatsSpikeWindowDays, cfg.StatsSpikeThreshold)
+
+	// Compute stars brought by releases
+	rs, err := mgoClient.getReleases(r.Tenant, r.Path)
 	if err != nil {
 		return err
 	}
-	e.Stats.PerDays = nil
-	if len(ms) > 0 {
-		for i := 30; i > 0; i-- {
-			if len(ms) >= i {
-				m := ms[len(ms)-i]
-				e.Stats.PerDays = append(e.Stats.PerDays, database.Measure{Date: m.Date, Count: m.Count})
-			}
-		}
+	e.Stats.Releases = computeReleases(rs, daily, cfg.StatsReleaseWindowDays)
+	annotateSpikesWithReleases(e.Stats.Spikes, e.Stats.Releases)
+
+	// Compute health from repository snapshots
+	snapshots, err := mgoClient.getRepositorySnapshots(r.Tenant, r.Path)
+	if err != nil {
+		return err
+	}
+	e.Stats.Health = computeHealth(snapshots, loc)
+
+	// Compute count per days stats for the last 30 days, starting at the first known day from last page. Counts per
+	// hours are kept to compute them in other timezones when stats are displayed.
+	ms, err := mgoClient.getRepoStarCountPerHours(r.Tenant, r.Path)
+	if err != nil {
+		return err
+	}
+	e.Stats.PerHours = make([]database.Measure, len(ms))
+	for i := range ms {
+		e.Stats.PerHours[i] = database.Measure{Date: ms[i].Date.UTC(), Count: ms[i].Count}
 	}
+	e.Stats.PerDays = database.LastDays(e.Stats.PerHours, loc, time.Now())
 
 	// Set last stargazers
-	ss, err := mgoClient.getLast10Stargazers(r.Path)
+	ss, err := mgoClient.getLastStargazers(r.Tenant, r.Path, 10)
 	if err != nil {
 		return err
 	}
@@ -243,9 +442,48 @@ func ComputeTaskRepositoryRoutine(pgClient *database.DB, mgoClient *DatabaseClie
 		e.Stats.Last10[i] = database.Stargazer{Name: ss[i].Data.User.Login}
 	}
 
+	// Compute stargazers breakdown and quality from known user profiles
+	all, err := mgoClient.getStargazers(r.Tenant, r.Path)
+	if err != nil {
+		return err
+	}
+	logins := make([]string, len(all))
+	for i := range all {
+		logins[i] = all[i].Data.User.Login
+	}
+	us, err := mgoClient.getUsers(r.Tenant, logins)
+	if err != nil {
+		return err
+	}
+	e.Stats.TopOrganizations = computeTopOrganizations(us)
+	e.Stats.TopCompanies = computeTopCompanies(us)
+	e.Stats.Countries = computeCountries(us)
+	e.Stats.Quality = computeQuality(all, us)
+
 	logrus.Debugf("execTaskRepositoryRoutine: end computing stats for repo for %s", e.Repository)
 
 	e.Status = database.StatusGenerated
 	e.LastGeneratedAt = time.Now()
-	return pgClient.Update(&e)
+	if e.FirstGeneratedAt == nil {
+		e.FirstGeneratedAt = &e.LastGeneratedAt
+	}
+	if err := pgClient.Update(&e); err != nil {
+		return err
+	}
+
+	// Keep generated stats to view them later
+	if err := pgClient.CreateSnapshot(&database.StatsSnapshot{
+		EntryID:     e.ID,
+		GeneratedAt: e.LastGeneratedAt,
+		Stats:       e.Stats,
+	}); err != nil {
+		return err
+	}
+	if cfg.StatsSnapshotsRetentionDays > 0 {
+		if err := pgClient.DeleteSnapshotsBefore(e.ID, e.LastGeneratedAt.AddDate(0, 0, -int(cfg.StatsSnapshotsRetentionDays))); err != nil {
+			return err
+		}
+	}
+
+	return enqueueWebhookDeliveries(pgClient, e, previous)
 }
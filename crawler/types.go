@@ -8,14 +8,29 @@ import (
 	"github.com/richardlt/stargazer/crawler/github"
 )
 
+// repository and all the data crawled for it are stored per tenant, a repository in two tenants is crawled for each.
 type repository struct {
-	ID   primitive.ObjectID `bson:"_id" json:"-"`
-	Path string             `bson:"path" json:"path"`
-	Data github.Repository  `bson:"data" json:"data"`
+	ID     primitive.ObjectID `bson:"_id" json:"-"`
+	Tenant string             `bson:"tenant" json:"-"`
+	Path   string             `bson:"path" json:"path"`
+	Data   github.Repository  `bson:"data" json:"data"`
+}
+
+type repositorySnapshot struct {
+	ID             primitive.ObjectID `bson:"_id" json:"-"`
+	Tenant         string             `bson:"tenant" json:"-"`
+	RepositoryPath string             `bson:"repository_path" json:"-"`
+	Date           time.Time          `bson:"date" json:"date"`
+	Stars          int64              `bson:"stars" json:"stars"`
+	Forks          int64              `bson:"forks" json:"forks"`
+	Watchers       int64              `bson:"watchers" json:"watchers"`
+	OpenIssues     int64              `bson:"open_issues" json:"open_issues"`
+	Size           int64              `bson:"size" json:"size"`
 }
 
 type stargazer struct {
 	ID             primitive.ObjectID `bson:"_id" json:"-"`
+	Tenant         string             `bson:"tenant" json:"-"`
 	RepositoryID   primitive.ObjectID `bson:"_repository_id" json:"-"`
 	RepositoryPath string             `bson:"repository_path" json:"-"`
 	Page           int64              `bson:"page" json:"page"`
@@ -23,8 +38,37 @@ type stargazer struct {
 	Data           github.Stargazer   `bson:"data" json:"data"`
 }
 
+// crawlSession records the stargazers pages loaded during a crawl, an uncompleted session is resumed by the next crawl.
+type crawlSession struct {
+	ID             primitive.ObjectID `bson:"_id" json:"-"`
+	Tenant         string             `bson:"tenant" json:"-"`
+	RepositoryID   primitive.ObjectID `bson:"_repository_id" json:"-"`
+	RepositoryPath string             `bson:"repository_path" json:"-"`
+	StartedAt      time.Time          `bson:"started_at" json:"started_at"`
+	CompletedAt    *time.Time         `bson:"completed_at" json:"completed_at"`
+	Pages          []crawlPage        `bson:"pages" json:"pages"`
+}
+
+type crawlPage struct {
+	Page     int64     `bson:"page" json:"page"`
+	ETag     string    `bson:"etag" json:"etag"`
+	Count    int64     `bson:"count" json:"count"`
+	First    time.Time `bson:"first" json:"first"`
+	Last     time.Time `bson:"last" json:"last"`
+	LastPage int64     `bson:"last_page" json:"last_page"`
+}
+
+type release struct {
+	ID             primitive.ObjectID `bson:"_id" json:"-"`
+	Tenant         string             `bson:"tenant" json:"-"`
+	RepositoryID   primitive.ObjectID `bson:"_repository_id" json:"-"`
+	RepositoryPath string             `bson:"repository_path" json:"-"`
+	Data           github.Release     `bson:"data" json:"data"`
+}
+
 type user struct {
 	ID            primitive.ObjectID    `bson:"_id" json:"-"`
+	Tenant        string                `bson:"tenant" json:"-"`
 	Expire        time.Time             `bson:"expire" json:"expire"`
 	Login         string                `bson:"login" json:"login"`
 	Data          github.User           `bson:"data" json:"data"`
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/crawler/github"
+)
+
+// refreshUser loads user's profile and organizations from Github if it is unknown or expired.
+// When the profile was not updated since last refresh, only its expiration is pushed back.
+func refreshUser(dbClient *DatabaseClient, ghClient github.Client, tenant, login string, userExpirationDelay int64) error {
+	u, err := dbClient.getUser(tenant, login)
+	if err != nil {
+		return err
+	}
+	needSave := u == nil || (u.Expire.Before(time.Now()) && userExpirationDelay > 0)
+	if !needSave {
+		return nil
+	}
+
+	logrus.Debugf("refreshUser: get user %s from Github", login)
+	o, err := ghClient.GetUser(login)
+	if err != nil {
+		return err
+	}
+	expire := time.Now().Add(time.Second * time.Duration(userExpirationDelay))
+
+	if u != nil && !o.UpdatedAt.IsZero() && o.UpdatedAt.Equal(u.Data.UpdatedAt) {
+		logrus.Debugf("refreshUser: user %s not updated since last refresh", login)
+		return dbClient.updateUserExpire(u.ID, expire)
+	}
+
+	os, err := ghClient.GetUserOrganizations(login)
+	if err != nil {
+		return err
+	}
+
+	if u == nil {
+		logrus.Debugf("refreshUser: insert user %s in database", login)
+		return dbClient.insertUser(&user{
+			Tenant:        tenant,
+			Expire:        expire,
+			Login:         login,
+			Data:          o,
+			Organizations: os,
+		})
+	}
+
+	u.Expire = expire
+	u.Data = o
+	u.Organizations = os
+	logrus.Debugf("refreshUser: update user %s in database", login)
+	return dbClient.updateUser(u)
+}
@@ -0,0 +1,107 @@
+package crawler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/crawler"
+	"github.com/richardlt/stargazer/crawler/github"
+	"github.com/richardlt/stargazer/crawler/mock_github"
+	"github.com/richardlt/stargazer/database"
+)
+
+type storedUser struct {
+	Expire        time.Time             `bson:"expire"`
+	Login         string                `bson:"login"`
+	Data          github.User           `bson:"data"`
+	Organizations []github.Organization `bson:"organizations"`
+}
+
+func TestEnrichStargazerUsersForRepo_refresh(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect(context.TODO()))
+	db := client.Database("stargazer")
+	mgo := crawler.NewMongoClient(db)
+	require.NoError(t, mgo.Init())
+
+	logins := []string{"unchanged", "changed", "unknown", "fresh"}
+	_, err = db.Collection("stargazers").DeleteMany(context.TODO(), bson.M{"tenant": config.DefaultTenant, "repository_path": "owner/refresh"})
+	require.NoError(t, err)
+	_, err = db.Collection("users").DeleteMany(context.TODO(), bson.M{"tenant": config.DefaultTenant, "login": bson.M{"$in": logins}})
+	require.NoError(t, err)
+	for i, login := range logins {
+		_, err := db.Collection("stargazers").InsertOne(context.TODO(), bson.M{
+			"_id":             primitive.NewObjectID(),
+			"tenant":          config.DefaultTenant,
+			"repository_path": "owner/refresh",
+			"page":            1,
+			"data":            bson.M{"user": bson.M{"login": login}, "starred_at": time.Date(2021, 1, 1, i, 0, 0, 0, time.UTC)},
+		})
+		require.NoError(t, err)
+	}
+
+	updatedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := time.Now().Add(-time.Hour)
+	for _, u := range []storedUser{
+		{Expire: expired, Login: "unchanged", Data: github.User{Login: "unchanged", Name: "Stored", UpdatedAt: updatedAt}, Organizations: []github.Organization{{Login: "old-org"}}},
+		{Expire: expired, Login: "changed", Data: github.User{Login: "changed", Name: "Stored", UpdatedAt: updatedAt}, Organizations: []github.Organization{{Login: "old-org"}}},
+		{Expire: time.Now().Add(time.Hour), Login: "fresh", Data: github.User{Login: "fresh"}},
+	} {
+		_, err := db.Collection("users").InsertOne(context.TODO(), bson.M{
+			"_id":           primitive.NewObjectID(),
+			"tenant":        config.DefaultTenant,
+			"expire":        u.Expire,
+			"login":         u.Login,
+			"data":          u.Data,
+			"organizations": u.Organizations,
+		})
+		require.NoError(t, err)
+	}
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	ghClient := mock_github.NewMockClient(ctrl)
+	e := database.Entry{Tenant: config.DefaultTenant, Repository: "owner/refresh"}
+	cfg := config.Crawler{TaskRepositoryEnrichUsersCount: 10, TaskRepositoryEnrichUsersShare: 1, UserExpirationDelay: 3600}
+
+	// Not expired users are not loaded, unchanged users don't have their organizations loaded
+	ghClient.EXPECT().GetRateLimit().Return(github.RateLimit{Limit: 5000, Remaining: 5000}).AnyTimes()
+	gomock.InOrder(
+		ghClient.EXPECT().GetUser("unknown").Return(github.User{Login: "unknown", Name: "Unknown"}, nil),
+		ghClient.EXPECT().GetUserOrganizations("unknown").Return(nil, nil),
+		ghClient.EXPECT().GetUser("changed").Return(github.User{Login: "changed", Name: "Changed", UpdatedAt: updatedAt.Add(time.Hour)}, nil),
+		ghClient.EXPECT().GetUserOrganizations("changed").Return([]github.Organization{{Login: "new-org"}}, nil),
+		ghClient.EXPECT().GetUser("unchanged").Return(github.User{Login: "unchanged", Name: "Not stored", UpdatedAt: updatedAt}, nil),
+	)
+	require.NoError(t, crawler.EnrichStargazerUsersForRepo(mgo, ghClient, cfg, e))
+
+	getUser := func(login string) storedUser {
+		var u storedUser
+		require.NoError(t, db.Collection("users").FindOne(context.TODO(), bson.M{"tenant": config.DefaultTenant, "login": login}).Decode(&u))
+		return u
+	}
+
+	u := getUser("unchanged")
+	assert.Equal(t, "Stored", u.Data.Name)
+	assert.Equal(t, []github.Organization{{Login: "old-org"}}, u.Organizations)
+	assert.True(t, u.Expire.After(time.Now()))
+
+	u = getUser("changed")
+	assert.Equal(t, "Changed", u.Data.Name)
+	assert.Equal(t, []github.Organization{{Login: "new-org"}}, u.Organizations)
+	assert.True(t, u.Expire.After(time.Now()))
+
+	u = getUser("unknown")
+	assert.Equal(t, "Unknown", u.Data.Name)
+}
@@ -0,0 +1,243 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/database"
+)
+
+type webhookPayload struct {
+	Event           database.WebhookEvent `json:"event"`
+	Tenant          string                `json:"tenant"`
+	Repository      string                `json:"repository"`
+	LastGeneratedAt time.Time             `json:"last_generated_at"`
+	CountStars      int64                 `json:"count_stars"`
+	Trend           *database.Trend       `json:"trend,omitempty"`
+	Milestone       *database.Milestone   `json:"milestone,omitempty"`
+	Spike           *database.Spike       `json:"spike,omitempty"`
+}
+
+// webhookPayloads returns the events for a stats generation. Milestones and spikes are only sent when they were not
+// known by the previous generation, nothing but the generation itself is sent the first time stats are generated.
+func webhookPayloads(e, previous database.Entry) []webhookPayload {
+	base := webhookPayload{
+		Tenant:          e.Tenant,
+		Repository:      e.Repository,
+		LastGeneratedAt: e.LastGeneratedAt,
+		CountStars:      e.Stats.CountStars,
+		Trend:           e.Stats.Trend,
+	}
+
+	generated := base
+	generated.Event = database.WebhookEventStatsGenerated
+	ps := []webhookPayload{generated}
+
+	if !previous.HasStats() {
+		return ps
+	}
+
+	var lastMilestone int64
+	for _, m := range previous.Stats.Milestones {
+		if m.Count > lastMilestone {
+			lastMilestone = m.Count
+		}
+	}
+	for i := range e.Stats.Milestones {
+		if e.Stats.Milestones[i].Count > lastMilestone {
+			p := base
+			p.Event = database.WebhookEventMilestone
+			p.Milestone = &e.Stats.Milestones[i]
+			ps = append(ps, p)
+		}
+	}
+
+	// Spike days are compared as periods of time and not as dates, the same spike can be on another date once the
+	// timezone changed
+	isKnownSpike := func(s database.Spike) bool {
+		for _, p := range previous.Stats.Spikes {
+			if s.Date.Before(p.Date.AddDate(0, 0, 1)) && p.Date.Before(s.Date.AddDate(0, 0, 1)) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range e.Stats.Spikes {
+		if !isKnownSpike(e.Stats.Spikes[i]) {
+			p := base
+			p.Event = database.WebhookEventSpike
+			p.Spike = &e.Stats.Spikes[i]
+			ps = append(ps, p)
+		}
+	}
+
+	return ps
+}
+
+// enqueueWebhookDeliveries queues a delivery of each event for the webhooks of the entry that subscribed to it.
+func enqueueWebhookDeliveries(pgClient *database.DB, e, previous database.Entry) error {
+	ws, err := pgClient.GetWebhooks(e.ID)
+	if err != nil {
+		return err
+	}
+	if len(ws) == 0 {
+		return nil
+	}
+
+	for _, p := range webhookPayloads(e, previous) {
+		buf, err := json.Marshal(p)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, w := range ws {
+			if !w.Events.Accepts(p.Event) {
+				continue
+			}
+			if err := pgClient.CreateDelivery(&database.WebhookDelivery{
+				WebhookID:     w.ID,
+				Event:         p.Event,
+				Payload:       string(buf),
+				Status:        database.DeliveryStatusPending,
+				NextAttemptAt: time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// signWebhookPayload returns the HMAC-SHA256 signature of a payload with the webhook's secret.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook posts the delivery's payload to the webhook and returns the response code.
+func deliverWebhook(httpClient *http.Client, w database.Webhook, d database.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewBufferString(d.Payload))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "stargazer-webhook")
+	req.Header.Set("X-Stargazer-Event", string(d.Event))
+	req.Header.Set("X-Stargazer-Delivery", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-Stargazer-Signature-256", signWebhookPayload(w.Secret, []byte(d.Payload)))
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, errors.Errorf("unexpected response code %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// recordWebhookAttempt updates a delivery after an attempt, failed attempts are retried with an exponential delay
+// until the max count of attempts is reached.
+func recordWebhookAttempt(d *database.WebhookDelivery, cfg config.Crawler, now time.Time, code int, err error) {
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseCode = code
+	d.Error = ""
+
+	if err == nil {
+		d.Status = database.DeliveryStatusDelivered
+		return
+	}
+
+	d.Error = err.Error()
+	if d.Attempts >= cfg.WebhookMaxAttempts {
+		d.Status = database.DeliveryStatusFailed
+		return
+	}
+	delay := time.Duration(cfg.WebhookRetryDelay) * time.Second << uint(d.Attempts-1)
+	d.NextAttemptAt = now.Add(delay)
+}
+
+func execWebhookDeliveriesRoutine(pgClient *database.DB, httpClient *http.Client, cfg config.Crawler) error {
+	ds, err := pgClient.GetDueDeliveries(time.Now(), 100)
+	if err != nil {
+		return err
+	}
+
+	for i := range ds {
+		w, err := pgClient.GetWebhook(ds[i].WebhookID)
+		if err != nil {
+			if errors.Cause(err) != gorm.ErrRecordNotFound {
+				return err
+			}
+			ds[i].Status = database.DeliveryStatusFailed
+			ds[i].Error = "webhook was deleted"
+			if err := pgClient.UpdateDelivery(&ds[i]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		code, err := deliverWebhook(httpClient, *w, ds[i])
+		if err != nil {
+			logrus.Warnf("execWebhookDeliveriesRoutine: delivery %d of %s to %s failed: %v", ds[i].ID, ds[i].Event, w.URL, err)
+		}
+		recordWebhookAttempt(&ds[i], cfg, time.Now(), code, err)
+		if err := pgClient.UpdateDelivery(&ds[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webhookHTTPClient returns a client that refuses to connect to internal addresses unless allowLocal is true. The check
+// is done on the resolved address when dialing so a host can't resolve to another address than the one checked at
+// registration.
+func webhookHTTPClient(allowLocal bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowLocal {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if ip := net.ParseIP(host); ip == nil || database.IsInternalIP(ip) {
+				return errors.Errorf("webhook delivery to internal address %s refused", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return fmt.Errorf("redirect to %s not followed", req.URL)
+		},
+	}
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/database"
+)
+
+func Test_webhookPayloads(t *testing.T) {
+	e := database.Entry{Repository: "foo/bar", Stats: database.Stats{
+		CountStars: 1200,
+		Timezone:   "UTC",
+		Milestones: []database.Milestone{{Count: 500, Date: day(2021, 1, 1)}, {Count: 1000, Date: day(2021, 3, 1)}},
+		Spikes:     []database.Spike{{Date: day(2021, 1, 10), Count: 50}, {Date: day(2021, 3, 2), Count: 80}},
+	}}
+
+	// Only the generation is sent the first time
+	ps := webhookPayloads(e, database.Entry{Status: database.StatusProcessing})
+	require.Len(t, ps, 1)
+	assert.Equal(t, database.WebhookEventStatsGenerated, ps[0].Event)
+	assert.Equal(t, int64(1200), ps[0].CountStars)
+
+	// New milestones and spikes are sent when stats were already generated
+	firstGeneratedAt := day(2021, 1, 1)
+	ps = webhookPayloads(e, database.Entry{
+		Status:           database.StatusProcessing,
+		FirstGeneratedAt: &firstGeneratedAt,
+		Stats: database.Stats{
+			Milestones: []database.Milestone{{Count: 500, Date: day(2021, 1, 1)}},
+			Spikes:     []database.Spike{{Date: day(2021, 1, 10), Count: 50}},
+		},
+	})
+	require.Len(t, ps, 3)
+	assert.Equal(t, database.WebhookEventStatsGenerated, ps[0].Event)
+	assert.Equal(t, database.WebhookEventMilestone, ps[1].Event)
+	assert.Equal(t, int64(1000), ps[1].Milestone.Count)
+	assert.Equal(t, database.WebhookEventSpike, ps[2].Event)
+	assert.Equal(t, day(2021, 3, 2), ps[2].Spike.Date)
+
+	// A known spike is not sent again when its date changed with the timezone, a spike the next day is
+	tokyo := time.FixedZone("Asia/Tokyo", 9*3600)
+	e.Stats.Spikes = []database.Spike{
+		{Date: time.Date(2021, 1, 11, 0, 0, 0, 0, tokyo), Count: 50},
+		{Date: time.Date(2021, 1, 12, 0, 0, 0, 0, tokyo), Count: 60},
+	}
+	ps = webhookPayloads(e, database.Entry{
+		Status:           database.StatusProcessing,
+		FirstGeneratedAt: &firstGeneratedAt,
+		Stats: database.Stats{
+			Milestones: []database.Milestone{{Count: 1000, Date: day(2021, 3, 1)}},
+			Spikes:     []database.Spike{{Date: day(2021, 1, 10), Count: 50}},
+		},
+	})
+	require.Len(t, ps, 2)
+	assert.Equal(t, database.WebhookEventSpike, ps[1].Event)
+	assert.Equal(t, int64(60), ps[1].Spike.Count)
+}
+
+func Test_deliverWebhook(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	code := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	w := database.Webhook{ID: "abc", URL: ts.URL, Secret: "s3cret"}
+	d := database.WebhookDelivery{ID: 42, Event: database.WebhookEventMilestone, Payload: `{"event":"milestone"}`}
+
+	res, err := deliverWebhook(ts.Client(), w, d)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res)
+	assert.Equal(t, `{"event":"milestone"}`, string(body))
+	assert.Equal(t, "milestone", received.Header.Get("X-Stargazer-Event"))
+	assert.Equal(t, "42", received.Header.Get("X-Stargazer-Delivery"))
+	// echo -n '{"event":"milestone"}' | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(t, "sha256=74b7409a039659e2a9608dd27b72709d7dd5e137d0a155d069f09fc861bc2eeb", received.Header.Get("X-Stargazer-Signature-256"))
+
+	code = http.StatusInternalServerError
+	res, err = deliverWebhook(ts.Client(), w, d)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, res)
+}
+
+func Test_recordWebhookAttempt(t *testing.T) {
+	cfg := config.Crawler{WebhookMaxAttempts: 3, WebhookRetryDelay: 60}
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	d := database.WebhookDelivery{Status: database.DeliveryStatusPending}
+
+	recordWebhookAttempt(&d, cfg, now, http.StatusBadGateway, errors.New("unexpected response code 502"))
+	assert.Equal(t, database.DeliveryStatusPending, d.Status)
+	assert.Equal(t, now.Add(time.Minute), d.NextAttemptAt)
+
+	recordWebhookAttempt(&d, cfg, now, 0, errors.New("timeout"))
+	assert.Equal(t, database.DeliveryStatusPending, d.Status)
+	assert.Equal(t, now.Add(2*time.Minute), d.NextAttemptAt)
+
+	recordWebhookAttempt(&d, cfg, now, 0, errors.New("timeout"))
+	assert.Equal(t, database.DeliveryStatusFailed, d.Status)
+	assert.Equal(t, int64(3), d.Attempts)
+
+	d = database.WebhookDelivery{Status: database.DeliveryStatusPending}
+	recordWebhookAttempt(&d, cfg, now, http.StatusNoContent, nil)
+	assert.Equal(t, database.DeliveryStatusDelivered, d.Status)
+	assert.Equal(t, http.StatusNoContent, d.ResponseCode)
+	assert.Empty(t, d.Error)
+}
+
+func Test_webhookHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	// Local receivers can't be reached by deliveries
+	d := database.WebhookDelivery{ID: 1, Event: database.WebhookEventStatsGenerated, Payload: `{}`}
+	_, err := deliverWebhook(webhookHTTPClient(false), database.Webhook{URL: ts.URL, Secret: "s3cret"}, d)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "internal address 127.0.0.1 refused")
+
+	_, err = deliverWebhook(webhookHTTPClient(false), database.Webhook{URL: "http://169.254.169.254/latest/meta-data/", Secret: "s3cret"}, d)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "internal address 169.254.169.254 refused")
+
+	// Unless local receivers are allowed
+	res, err := deliverWebhook(webhookHTTPClient(true), database.Webhook{URL: ts.URL, Secret: "s3cret"}, d)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res)
+}
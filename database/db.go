@@ -14,11 +14,15 @@ func New(databaseURL string) (*DB, error) {
 		return nil, errors.Wrap(err, "can't connect to database")
 	}
 
-	res := db.AutoMigrate(&Entry{})
+	res := db.AutoMigrate(&Entry{}, &StatsSnapshot{}, &Webhook{}, &WebhookDelivery{}, &Migration{})
 	if res.Error != nil {
 		return nil, errors.WithStack(res.Error)
 	}
 
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
 	return &DB{db: db}, nil
 }
 
@@ -30,18 +34,18 @@ func (d *DB) Close() {
 	d.db.Close()
 }
 
-func (d *DB) Get(repo string) (*Entry, error) {
+func (d *DB) Get(tenant, repo string) (*Entry, error) {
 	var e Entry
-	res := d.db.First(&e, "repository = ?", repo)
+	res := d.db.First(&e, "tenant = ? AND repository = ?", tenant, repo)
 	if res.Error != nil {
 		return nil, errors.WithStack(res.Error)
 	}
 	return &e, nil
 }
 
-func (d *DB) GetAllWithStatus(status Status) ([]Entry, error) {
+func (d *DB) GetAllWithStatus(status ...Status) ([]Entry, error) {
 	var es []Entry
-	res := d.db.Find(&es, "status = ?", status)
+	res := d.db.Find(&es, "status IN (?)", status)
 	if res.Error != nil {
 		return nil, errors.WithStack(res.Error)
 	}
@@ -53,19 +57,131 @@ func (d *DB) Create(e *Entry) error {
 	return errors.WithStack(res.Error)
 }
 
+// Update saves the entry except its token that is only changed by UpdateToken, so an entry loaded before the token was
+// replaced doesn't restore the old one.
 func (d *DB) Update(e *Entry) error {
-	res := d.db.Save(e)
+	res := d.db.Omit("token").Save(e)
 	e.UpdatedAt = time.Now()
 	return errors.WithStack(res.Error)
 }
 
-func (d *DB) Delete(repo string) error {
-	res := d.db.Exec("DELETE FROM entries WHERE repository = ?", repo)
+func (d *DB) UpdateToken(e *Entry, token string) error {
+	res := d.db.Model(e).UpdateColumn("token", token)
+	e.Token = token
+	return errors.WithStack(res.Error)
+}
+
+func (d *DB) Delete(tenant, repo string) error {
+	res := d.db.Exec("DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT webhooks.id FROM webhooks JOIN entries ON entries.id = webhooks.entry_id WHERE tenant = ? AND repository = ?)", tenant, repo)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	res = d.db.Exec("DELETE FROM webhooks WHERE entry_id IN (SELECT id FROM entries WHERE tenant = ? AND repository = ?)", tenant, repo)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	res = d.db.Exec("DELETE FROM stats_snapshots WHERE entry_id IN (SELECT id FROM entries WHERE tenant = ? AND repository = ?)", tenant, repo)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	res = d.db.Exec("DELETE FROM entries WHERE tenant = ? AND repository = ?", tenant, repo)
 	return errors.WithStack(res.Error)
 }
 
-func (d *DB) Count() (int64, error) {
+func (d *DB) Count(tenant string) (int64, error) {
 	var count int64
-	res := d.db.Table("entries").Count(&count)
+	res := d.db.Table("entries").Where("tenant = ?", tenant).Count(&count)
 	return count, errors.WithStack(res.Error)
 }
+
+func (d *DB) CreateSnapshot(s *StatsSnapshot) error {
+	res := d.db.Create(s)
+	return errors.WithStack(res.Error)
+}
+
+// GetSnapshotAt returns the last snapshot generated before given time for an entry.
+func (d *DB) GetSnapshotAt(entryID uint, at time.Time) (*StatsSnapshot, error) {
+	var s StatsSnapshot
+	res := d.db.Where("entry_id = ? AND generated_at < ?", entryID, at).Order("generated_at DESC").First(&s)
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	return &s, nil
+}
+
+// GetSnapshots returns generation dates of all snapshots for an entry without their stats.
+func (d *DB) GetSnapshots(entryID uint) ([]StatsSnapshot, error) {
+	var ss []StatsSnapshot
+	res := d.db.Select("id, entry_id, generated_at").Where("entry_id = ?", entryID).Order("generated_at").Find(&ss)
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	return ss, nil
+}
+
+func (d *DB) DeleteSnapshotsBefore(entryID uint, before time.Time) error {
+	res := d.db.Exec("DELETE FROM stats_snapshots WHERE entry_id = ? AND generated_at < ?", entryID, before)
+	return errors.WithStack(res.Error)
+}
+
+func (d *DB) CreateWebhook(w *Webhook) error {
+	res := d.db.Create(w)
+	return errors.WithStack(res.Error)
+}
+
+func (d *DB) GetWebhook(id string) (*Webhook, error) {
+	var w Webhook
+	res := d.db.First(&w, "id = ?", id)
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	return &w, nil
+}
+
+func (d *DB) GetWebhooks(entryID uint) ([]Webhook, error) {
+	var ws []Webhook
+	res := d.db.Where("entry_id = ?", entryID).Order("created_at").Find(&ws)
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	return ws, nil
+}
+
+func (d *DB) DeleteWebhook(id string) error {
+	res := d.db.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	res = d.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	return errors.WithStack(res.Error)
+}
+
+func (d *DB) CreateDelivery(dl *WebhookDelivery) error {
+	res := d.db.Create(dl)
+	return errors.WithStack(res.Error)
+}
+
+func (d *DB) UpdateDelivery(dl *WebhookDelivery) error {
+	res := d.db.Save(dl)
+	return errors.WithStack(res.Error)
+}
+
+// GetDueDeliveries returns pending deliveries that should be attempted at given time, oldest first.
+func (d *DB) GetDueDeliveries(now time.Time, limit int64) ([]WebhookDelivery, error) {
+	var ds []WebhookDelivery
+	res := d.db.Where("status = ? AND next_attempt_at <= ?", DeliveryStatusPending, now).Order("next_attempt_at").Limit(limit).Find(&ds)
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	return ds, nil
+}
+
+// GetDeliveries returns the last deliveries for a webhook, most recent first.
+func (d *DB) GetDeliveries(webhookID string, limit int64) ([]WebhookDelivery, error) {
+	var ds []WebhookDelivery
+	res := d.db.Where("webhook_id = ?", webhookID).Order("created_at DESC").Limit(limit).Find(&ds)
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	return ds, nil
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Migration records a one-off change of the database that was applied.
+type Migration struct {
+	Name      string `gorm:"primary_key"`
+	AppliedAt time.Time
+}
+
+type migration struct {
+	name  string
+	apply func(tx *gorm.DB) error
+}
+
+// migrations are applied in order after the auto migration, only once for a database.
+var migrations = []migration{
+	{
+		// Entries are now unique by tenant and repository
+		name: "drop-uix-entries-repository",
+		apply: func(tx *gorm.DB) error {
+			return tx.Exec("DROP INDEX IF EXISTS uix_entries_repository").Error
+		},
+	},
+	{
+		// The timezone is given for each request and not stored with the entry anymore
+		name: "drop-entries-timezone",
+		apply: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE entries DROP COLUMN IF EXISTS timezone").Error
+		},
+	},
+	{
+		// Entries generated before the first generation date was stored are the ones with snapshots or generated again
+		// after their creation
+		name: "backfill-entries-first-generated-at",
+		apply: func(tx *gorm.DB) error {
+			return tx.Exec(`UPDATE entries SET first_generated_at = COALESCE(
+				(SELECT MIN(generated_at) FROM stats_snapshots WHERE entry_id = entries.id), last_generated_at)
+				WHERE first_generated_at IS NULL AND (status = ? OR last_generated_at > created_at
+				OR EXISTS (SELECT 1 FROM stats_snapshots WHERE entry_id = entries.id))`, StatusGenerated).Error
+		},
+	},
+	{
+		// Entries created before tokens existed get one so their owner can register webhooks
+		name: "backfill-entries-token",
+		apply: func(tx *gorm.DB) error {
+			var ids []uint
+			if err := tx.Model(&Entry{}).Where("token IS NULL OR token = ''").Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			for _, id := range ids {
+				token, err := NewToken()
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&Entry{}).Where("id = ?", id).UpdateColumn("token", token).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+func migrate(db *gorm.DB) error {
+	for _, m := range migrations {
+		tx := db.Begin()
+		if tx.Error != nil {
+			return errors.WithStack(tx.Error)
+		}
+
+		// The insert waits for a concurrent transaction applying the same migration, then does nothing
+		res := tx.Exec("INSERT INTO migrations (name, applied_at) VALUES (?, ?) ON CONFLICT DO NOTHING", m.name, time.Now())
+		if res.Error != nil {
+			tx.Rollback()
+			return errors.WithStack(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			tx.Rollback()
+			continue
+		}
+
+		if err := m.apply(tx); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "can't apply migration %s", m.name)
+		}
+		if err := tx.Commit().Error; err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
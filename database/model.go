@@ -1,8 +1,11 @@
 package database
 
 import (
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -11,26 +14,69 @@ import (
 type Status string
 
 const (
-	StatusRequested Status = "requested"
-	StatusGenerated Status = "generated"
+	StatusRequested  Status = "requested"
+	StatusProcessing Status = "processing"
+	StatusGenerated  Status = "generated"
 )
 
 type Entry struct {
-	ID              uint      `gorm:"column:id;primary_key"`
-	CreatedAt       time.Time `gorm:"column:created_at;DEFAULT:CURRENT_TIMESTAMP"`
-	UpdatedAt       time.Time `gorm:"column:updated_at;DEFAULT:CURRENT_TIMESTAMP"`
-	Repository      string    `gorm:"column:repository;type:varchar(255);unique_index"`
-	LastGeneratedAt time.Time `gorm:"column:last_generated_at;DEFAULT:CURRENT_TIMESTAMP"`
-	LastRequestedAt time.Time `gorm:"column:last_requested_at;DEFAULT:CURRENT_TIMESTAMP"`
-	Status          Status    `gorm:"column:status"`
-	Stats           Stats     `gorm:"column:stats;type:JSONB"`
+	ID               uint       `gorm:"column:id;primary_key"`
+	CreatedAt        time.Time  `gorm:"column:created_at;DEFAULT:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at;DEFAULT:CURRENT_TIMESTAMP"`
+	Tenant           string     `gorm:"column:tenant;type:varchar(255);unique_index:uix_entries_tenant_repository;DEFAULT:'default'"`
+	Repository       string     `gorm:"column:repository;type:varchar(255);unique_index:uix_entries_tenant_repository"`
+	LastGeneratedAt  time.Time  `gorm:"column:last_generated_at;DEFAULT:CURRENT_TIMESTAMP"`
+	FirstGeneratedAt *time.Time `gorm:"column:first_generated_at"`
+	LastRequestedAt  time.Time  `gorm:"column:last_requested_at;DEFAULT:CURRENT_TIMESTAMP"`
+	Status           Status     `gorm:"column:status"`
+	Token            string     `gorm:"column:token;type:varchar(64)"`
+	Stats            Stats      `gorm:"column:stats;type:JSONB"`
+}
+
+// HasStats checks if stats were generated at least once for the entry, they are kept when generated again. The first
+// generation date is only set once stats were generated.
+func (e Entry) HasStats() bool {
+	return e.Status == StatusGenerated || e.FirstGeneratedAt != nil
+}
+
+// NewToken returns a random hex token.
+func NewToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 type Stats struct {
-	Evolution  []Measure   `json:"evolution,omitempty"`
-	PerDays    []Measure   `json:"per_days,omitempty"`
-	Last10     []Stargazer `json:"last_10,omitempty"`
-	CountStars int64       `json:"count_stars,omitempty"`
+	Evolution        []Measure   `json:"evolution,omitempty"`
+	EvolutionBands   []Band      `json:"evolution_bands,omitempty"`
+	PerDays          []Measure   `json:"per_days,omitempty"`
+	PerHours         []Measure   `json:"per_hours,omitempty"`
+	PerWeeks         []Measure   `json:"per_weeks,omitempty"`
+	PerMonths        []Measure   `json:"per_months,omitempty"`
+	Last10           []Stargazer `json:"last_10,omitempty"`
+	CountStars       int64       `json:"count_stars,omitempty"`
+	Timezone         string      `json:"timezone,omitempty"`
+	TopOrganizations []Breakdown `json:"top_organizations,omitempty"`
+	TopCompanies     []Breakdown `json:"top_companies,omitempty"`
+	Countries        []Country   `json:"countries,omitempty"`
+	Quality          *Quality    `json:"quality,omitempty"`
+	Trend            *Trend      `json:"trend,omitempty"`
+	Milestones       []Milestone `json:"milestones,omitempty"`
+	Forecast         *Forecast   `json:"forecast,omitempty"`
+	Spikes           []Spike     `json:"spikes,omitempty"`
+	Releases         []Release   `json:"releases,omitempty"`
+	Health           []Health    `json:"health,omitempty"`
+}
+
+// CrawledEvolution returns the evolution without its last measure, that is the current count of stars of the repository
+// and not a count of crawled stargazers.
+func (s Stats) CrawledEvolution() []Measure {
+	if len(s.Evolution) == 0 {
+		return nil
+	}
+	return s.Evolution[:len(s.Evolution)-1]
 }
 
 func (s *Stats) Scan(src interface{}) error {
@@ -51,6 +97,100 @@ type Measure struct {
 	Count int64     `json:"count"`
 }
 
+type Trend struct {
+	Last7Days      int64    `json:"last_7_days"`
+	Last30Days     int64    `json:"last_30_days"`
+	Last90Days     int64    `json:"last_90_days"`
+	WeekOverWeek   *float64 `json:"week_over_week,omitempty"`
+	RollingAverage float64  `json:"rolling_average"`
+	BestDay        *Measure `json:"best_day,omitempty"`
+	BestWeek       *Measure `json:"best_week,omitempty"`
+}
+
+type Band struct {
+	Date  time.Time `json:"date"`
+	Lower int64     `json:"lower"`
+	Upper int64     `json:"upper"`
+}
+
+type Milestone struct {
+	Count int64     `json:"count"`
+	Date  time.Time `json:"date"`
+}
+
+type Forecast struct {
+	Milestone   int64      `json:"milestone"`
+	Date        time.Time  `json:"date"`
+	Earliest    time.Time  `json:"earliest"`
+	Latest      *time.Time `json:"latest,omitempty"`
+	StarsPerDay float64    `json:"stars_per_day"`
+}
+
+type Spike struct {
+	Date  time.Time `json:"date"`
+	Count int64     `json:"count"`
+	Mean  float64   `json:"mean"`
+	Score float64   `json:"score"`
+	Label string    `json:"label"`
+}
+
+type Release struct {
+	Name       string    `json:"name"`
+	TagName    string    `json:"tag_name"`
+	Date       time.Time `json:"date"`
+	StarsAfter int64     `json:"stars_after"`
+}
+
+type Health struct {
+	Date       time.Time `json:"date"`
+	Stars      int64     `json:"stars"`
+	Forks      int64     `json:"forks"`
+	Watchers   int64     `json:"watchers"`
+	OpenIssues int64     `json:"open_issues"`
+	Size       int64     `json:"size"`
+}
+
 type Stargazer struct {
 	Name string `json:"by"`
 }
+
+type Breakdown struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type Country struct {
+	Code  string  `json:"code"`
+	Name  string  `json:"name"`
+	Count int64   `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// Flag returns the emoji flag for the country.
+func (c Country) Flag() string {
+	if len(c.Code) != 2 {
+		return ""
+	}
+	var flag []rune
+	for _, r := range strings.ToUpper(c.Code) {
+		flag = append(flag, 0x1F1E6+(r-'A'))
+	}
+	return string(flag)
+}
+
+type Quality struct {
+	Score         *float64           `json:"score,omitempty"`
+	Analyzed      int64              `json:"analyzed"`
+	WithProfile   int64              `json:"with_profile"`
+	Bots          int64              `json:"bots"`
+	NewAccounts   int64              `json:"new_accounts"`
+	NoActivity    int64              `json:"no_activity"`
+	EmptyProfiles int64              `json:"empty_profiles"`
+	Bursts        int64              `json:"bursts"`
+	Flagged       []FlaggedStargazer `json:"flagged,omitempty"`
+}
+
+type FlaggedStargazer struct {
+	Name    string   `json:"by"`
+	Reasons []string `json:"reasons"`
+}
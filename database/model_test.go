@@ -0,0 +1,19 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func TestEntry_HasStats(t *testing.T) {
+	generatedAt := day(2021, 1, 2)
+	assert.False(t, database.Entry{Status: database.StatusRequested}.HasStats())
+	assert.True(t, database.Entry{Status: database.StatusGenerated}.HasStats())
+	assert.True(t, database.Entry{Status: database.StatusRequested, FirstGeneratedAt: &generatedAt}.HasStats())
+
+	// Generation dates from another clock than the creation date are not used
+	assert.False(t, database.Entry{Status: database.StatusRequested, CreatedAt: day(2021, 1, 1), LastGeneratedAt: day(2021, 1, 2)}.HasStats())
+}
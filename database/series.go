@@ -0,0 +1,110 @@
+package database
+
+import "time"
+
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+// StartOf returns the start of the period that contains given date, in date's location.
+// Weeks are ISO weeks that start on Monday.
+func (p Period) StartOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch p {
+	case PeriodWeek:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Next returns the start of the period that follows given period start.
+func (p Period) Next(t time.Time) time.Time {
+	switch p {
+	case PeriodWeek:
+		return t.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// FillDays returns one measure per day between from and to, days without measure have a zero count.
+func FillDays(ms []Measure, from, to time.Time) []Measure {
+	counts := make(map[time.Time]int64, len(ms))
+	for i := range ms {
+		counts[PeriodDay.StartOf(ms[i].Date.In(from.Location()))] += ms[i].Count
+	}
+
+	var res []Measure
+	for d := PeriodDay.StartOf(from); !d.After(to); d = PeriodDay.Next(d) {
+		res = append(res, Measure{Date: d, Count: counts[d]})
+	}
+	return res
+}
+
+// LastDays returns the count of stars per day in given location for the 30 days until given date, or from the first
+// measure if more recent. Measures are counts per hours so the days can be computed in any timezone.
+func LastDays(perHours []Measure, loc *time.Location, to time.Time) []Measure {
+	if len(perHours) == 0 {
+		return nil
+	}
+	to = to.In(loc)
+	from := to.AddDate(0, 0, -29)
+	if first := perHours[0].Date.In(loc); first.After(from) {
+		from = first
+	}
+	return FillDays(perHours, from, to)
+}
+
+// DailySeries converts a cumulative evolution to the count of stars per day, days without stars have a zero count.
+func DailySeries(evolution []Measure) []Measure {
+	if len(evolution) == 0 {
+		return nil
+	}
+
+	// Keep the last cumulative count for each day, in the location of the first measure
+	loc := evolution[0].Date.Location()
+	cumulative := make(map[time.Time]int64, len(evolution))
+	for i := range evolution {
+		cumulative[PeriodDay.StartOf(evolution[i].Date.In(loc))] = evolution[i].Count
+	}
+
+	var res []Measure
+	var previous int64
+	last := PeriodDay.StartOf(evolution[len(evolution)-1].Date.In(loc))
+	for d := PeriodDay.StartOf(evolution[0].Date); !d.After(last); d = PeriodDay.Next(d) {
+		count, ok := cumulative[d]
+		if !ok {
+			count = previous
+		}
+		diff := count - previous
+		if diff < 0 {
+			diff = 0
+		}
+		res = append(res, Measure{Date: d, Count: diff})
+		previous = count
+	}
+	return res
+}
+
+// AggregateSeries sums daily measures by period, periods without stars have a zero count.
+func AggregateSeries(daily []Measure, p Period) []Measure {
+	var res []Measure
+	for i := range daily {
+		start := p.StartOf(daily[i].Date)
+		for len(res) > 0 && p.Next(res[len(res)-1].Date).Before(start) {
+			res = append(res, Measure{Date: p.Next(res[len(res)-1].Date)})
+		}
+		if len(res) == 0 || !res[len(res)-1].Date.Equal(start) {
+			res = append(res, Measure{Date: start})
+		}
+		res[len(res)-1].Count += daily[i].Count
+	}
+	return res
+}
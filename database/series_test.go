@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriod_StartOf(t *testing.T) {
+	date := time.Date(2021, 3, 3, 15, 4, 5, 0, time.UTC) // Wednesday
+	assert.Equal(t, day(2021, 3, 3), database.PeriodDay.StartOf(date))
+	assert.Equal(t, day(2021, 3, 1), database.PeriodWeek.StartOf(date))
+	assert.Equal(t, day(2021, 3, 1), database.PeriodMonth.StartOf(date))
+	assert.Equal(t, day(2021, 2, 22), database.PeriodWeek.StartOf(day(2021, 2, 28))) // Sunday
+}
+
+func TestFillDays(t *testing.T) {
+	ms := []database.Measure{
+		{Date: day(2021, 1, 2), Count: 3},
+		{Date: day(2021, 1, 4), Count: 1},
+	}
+	assert.Equal(t, []database.Measure{
+		{Date: day(2021, 1, 1), Count: 0},
+		{Date: day(2021, 1, 2), Count: 3},
+		{Date: day(2021, 1, 3), Count: 0},
+		{Date: day(2021, 1, 4), Count: 1},
+		{Date: day(2021, 1, 5), Count: 0},
+	}, database.FillDays(ms, day(2021, 1, 1), time.Date(2021, 1, 5, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestLastDays(t *testing.T) {
+	perHours := []database.Measure{
+		{Date: time.Date(2021, 6, 2, 3, 0, 0, 0, time.UTC), Count: 2},
+		{Date: time.Date(2021, 6, 2, 20, 0, 0, 0, time.UTC), Count: 1},
+	}
+	to := time.Date(2021, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []database.Measure{
+		{Date: day(2021, 6, 2), Count: 3},
+		{Date: day(2021, 6, 3), Count: 0},
+	}, database.LastDays(perHours, time.UTC, to))
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	assert.NoError(t, err)
+	assert.Equal(t, []database.Measure{
+		{Date: time.Date(2021, 6, 1, 0, 0, 0, 0, loc), Count: 2},
+		{Date: time.Date(2021, 6, 2, 0, 0, 0, 0, loc), Count: 1},
+		{Date: time.Date(2021, 6, 3, 0, 0, 0, 0, loc), Count: 0},
+	}, database.LastDays(perHours, loc, to))
+
+	// Only the last 30 days are kept
+	assert.Len(t, database.LastDays(perHours, time.UTC, to.AddDate(0, 2, 0)), 30)
+	assert.Nil(t, database.LastDays(nil, time.UTC, to))
+}
+
+func TestDailySeries(t *testing.T) {
+	evolution := []database.Measure{
+		{Date: day(2021, 1, 1), Count: 2},
+		{Date: day(2021, 1, 1), Count: 5},
+		{Date: day(2021, 1, 3), Count: 6},
+		{Date: time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC), Count: 9},
+	}
+	assert.Equal(t, []database.Measure{
+		{Date: day(2021, 1, 1), Count: 5},
+		{Date: day(2021, 1, 2), Count: 0},
+		{Date: day(2021, 1, 3), Count: 1},
+		{Date: day(2021, 1, 4), Count: 3},
+	}, database.DailySeries(evolution))
+	assert.Nil(t, database.DailySeries(nil))
+}
+
+func TestAggregateSeries(t *testing.T) {
+	daily := []database.Measure{
+		{Date: day(2021, 1, 30), Count: 1},
+		{Date: day(2021, 1, 31), Count: 2},
+		{Date: day(2021, 2, 1), Count: 3},
+		{Date: day(2021, 4, 2), Count: 4},
+	}
+	assert.Equal(t, []database.Measure{
+		{Date: day(2021, 1, 1), Count: 3},
+		{Date: day(2021, 2, 1), Count: 3},
+		{Date: day(2021, 3, 1), Count: 0},
+		{Date: day(2021, 4, 1), Count: 4},
+	}, database.AggregateSeries(daily, database.PeriodMonth))
+
+	weeks := database.AggregateSeries(daily, database.PeriodWeek)
+	assert.Equal(t, database.Measure{Date: day(2021, 1, 25), Count: 3}, weeks[0])
+	assert.Equal(t, database.Measure{Date: day(2021, 2, 1), Count: 3}, weeks[1])
+	assert.Equal(t, database.Measure{Date: day(2021, 2, 8), Count: 0}, weeks[2])
+	assert.Equal(t, database.Measure{Date: day(2021, 3, 29), Count: 4}, weeks[len(weeks)-1])
+	assert.Len(t, weeks, 10)
+}
+
+func TestStats_CrawledEvolution(t *testing.T) {
+	assert.Nil(t, database.Stats{}.CrawledEvolution())
+
+	s := database.Stats{Evolution: []database.Measure{
+		{Date: day(2021, 1, 1), Count: 100},
+		{Date: day(2021, 1, 2), Count: 200},
+		{Date: day(2021, 3, 1), Count: 50000},
+	}}
+	assert.Equal(t, []database.Measure{{Date: day(2021, 1, 1), Count: 100}, {Date: day(2021, 1, 2), Count: 200}}, s.CrawledEvolution())
+}
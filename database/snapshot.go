@@ -0,0 +1,60 @@
+package database
+
+import "time"
+
+// StatsSnapshot keeps the stats of an entry as they were generated at a given time.
+type StatsSnapshot struct {
+	ID          uint      `gorm:"column:id;primary_key" json:"-"`
+	EntryID     uint      `gorm:"column:entry_id;index:idx_stats_snapshots_entry_generated_at" json:"-"`
+	GeneratedAt time.Time `gorm:"column:generated_at;index:idx_stats_snapshots_entry_generated_at" json:"generated_at"`
+	Stats       Stats     `gorm:"column:stats;type:JSONB" json:"stats,omitempty"`
+}
+
+type StatsDiff struct {
+	From          time.Time   `json:"from"`
+	To            time.Time   `json:"to"`
+	Stars         int64       `json:"stars"`
+	Forks         int64       `json:"forks"`
+	Watchers      int64       `json:"watchers"`
+	OpenIssues    int64       `json:"open_issues"`
+	NewStargazers []Stargazer `json:"new_stargazers,omitempty"`
+	Milestones    []Milestone `json:"milestones,omitempty"`
+}
+
+// DiffStats returns what changed between two snapshots.
+func DiffStats(from, to StatsSnapshot) StatsDiff {
+	d := StatsDiff{
+		From:  from.GeneratedAt,
+		To:    to.GeneratedAt,
+		Stars: to.Stats.CountStars - from.Stats.CountStars,
+	}
+
+	if len(from.Stats.Health) > 0 && len(to.Stats.Health) > 0 {
+		f, t := from.Stats.Health[len(from.Stats.Health)-1], to.Stats.Health[len(to.Stats.Health)-1]
+		d.Forks = t.Forks - f.Forks
+		d.Watchers = t.Watchers - f.Watchers
+		d.OpenIssues = t.OpenIssues - f.OpenIssues
+	}
+
+	known := make(map[string]bool, len(from.Stats.Last10))
+	for _, s := range from.Stats.Last10 {
+		known[s.Name] = true
+	}
+	for _, s := range to.Stats.Last10 {
+		if !known[s.Name] {
+			d.NewStargazers = append(d.NewStargazers, s)
+		}
+	}
+
+	reached := make(map[int64]bool, len(from.Stats.Milestones))
+	for _, m := range from.Stats.Milestones {
+		reached[m.Count] = true
+	}
+	for _, m := range to.Stats.Milestones {
+		if !reached[m.Count] {
+			d.Milestones = append(d.Milestones, m)
+		}
+	}
+
+	return d
+}
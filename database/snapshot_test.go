@@ -0,0 +1,39 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func TestDiffStats(t *testing.T) {
+	from := database.StatsSnapshot{
+		GeneratedAt: day(2021, 1, 1),
+		Stats: database.Stats{
+			CountStars: 95,
+			Last10:     []database.Stargazer{{Name: "a"}, {Name: "b"}},
+			Health:     []database.Health{{Forks: 3, Watchers: 2, OpenIssues: 5}},
+		},
+	}
+	to := database.StatsSnapshot{
+		GeneratedAt: day(2021, 1, 8),
+		Stats: database.Stats{
+			CountStars: 102,
+			Last10:     []database.Stargazer{{Name: "c"}, {Name: "a"}, {Name: "b"}},
+			Milestones: []database.Milestone{{Count: 100, Date: day(2021, 1, 6)}},
+			Health:     []database.Health{{Forks: 4, Watchers: 2, OpenIssues: 3}},
+		},
+	}
+
+	assert.Equal(t, database.StatsDiff{
+		From:          day(2021, 1, 1),
+		To:            day(2021, 1, 8),
+		Stars:         7,
+		Forks:         1,
+		OpenIssues:    -2,
+		NewStargazers: []database.Stargazer{{Name: "c"}},
+		Milestones:    []database.Milestone{{Count: 100, Date: day(2021, 1, 6)}},
+	}, database.DiffStats(from, to))
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CrawledStargazer is a stargazer stored by the crawler, with the stargazers page it comes from.
+type CrawledStargazer struct {
+	Login     string
+	AvatarURL string
+	StarredAt time.Time
+	Page      int64
+}
+
+type crawledStargazerDocument struct {
+	Page int64 `bson:"page"`
+	Data struct {
+		User struct {
+			Login     string `bson:"login"`
+			AvatarURL string `bson:"avatar_url"`
+		} `bson:"user"`
+		StarredAt time.Time `bson:"starred_at"`
+	} `bson:"data"`
+}
+
+func (d crawledStargazerDocument) stargazer() CrawledStargazer {
+	return CrawledStargazer{Login: d.Data.User.Login, AvatarURL: d.Data.User.AvatarURL, StarredAt: d.Data.StarredAt, Page: d.Page}
+}
+
+// NewStargazerStore returns a read only access to the stargazers stored by the crawler in Mongo.
+func NewStargazerStore(db *mongo.Database) *StargazerStore {
+	return &StargazerStore{db: db}
+}
+
+type StargazerStore struct {
+	db *mongo.Database
+}
+
+// Iterate calls given callback for each stored stargazer of a tenant's repository ordered by star date.
+func (s *StargazerStore) Iterate(tenant, repo string, callback func(CrawledStargazer) error) error {
+	co := s.db.Collection("stargazers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "repository_path": repo}, &options.FindOptions{
+		Sort: bson.M{"data.starred_at": 1},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var d crawledStargazerDocument
+		if err := cur.Decode(&d); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := callback(d.stargazer()); err != nil {
+			return err
+		}
+	}
+	return errors.WithStack(cur.Err())
+}
+
+// GetLatest returns stored stargazers of a tenant's repository from the most recent one, with the count of all stored
+// stargazers for the repository.
+func (s *StargazerStore) GetLatest(tenant, repo string, skip, limit int64) ([]CrawledStargazer, int64, error) {
+	co := s.db.Collection("stargazers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := co.CountDocuments(ctx, bson.M{"tenant": tenant, "repository_path": repo})
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	cur, err := co.Find(ctx, bson.M{"tenant": tenant, "repository_path": repo}, &options.FindOptions{
+		Sort:  bson.M{"data.starred_at": -1},
+		Skip:  &skip,
+		Limit: &limit,
+	})
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	var ds []crawledStargazerDocument
+	if err := cur.All(ctx, &ds); err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	res := make([]CrawledStargazer, len(ds))
+	for i := range ds {
+		res[i] = ds[i].stargazer()
+	}
+	return res, count, nil
+}
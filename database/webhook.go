@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookEventStatsGenerated WebhookEvent = "stats_generated"
+	WebhookEventMilestone      WebhookEvent = "milestone"
+	WebhookEventSpike          WebhookEvent = "spike"
+)
+
+var WebhookEvents = []WebhookEvent{WebhookEventStatsGenerated, WebhookEventMilestone, WebhookEventSpike}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+// Webhook is an URL registered for an entry that receives events signed with its secret.
+type Webhook struct {
+	ID        string        `gorm:"column:id;type:varchar(32);primary_key" json:"id"`
+	CreatedAt time.Time     `gorm:"column:created_at;DEFAULT:CURRENT_TIMESTAMP" json:"created_at"`
+	EntryID   uint          `gorm:"column:entry_id;index:idx_webhooks_entry_id" json:"-"`
+	URL       string        `gorm:"column:url;type:text" json:"url"`
+	Secret    string        `gorm:"column:secret;type:varchar(255)" json:"-"`
+	Events    WebhookFilter `gorm:"column:events;type:JSONB" json:"events"`
+}
+
+type WebhookFilter []WebhookEvent
+
+// Accepts checks if the event was subscribed.
+func (f WebhookFilter) Accepts(event WebhookEvent) bool {
+	for _, e := range f {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *WebhookFilter) Scan(src interface{}) error {
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("type assertion .([]byte) failed")
+	}
+	return errors.Wrap(json.Unmarshal(source, f), "cannot unmarshal WebhookFilter")
+}
+
+func (f WebhookFilter) Value() (driver.Value, error) {
+	j, err := json.Marshal(f)
+	return j, errors.Wrap(err, "cannot marshal WebhookFilter")
+}
+
+// WebhookDelivery is an event queued for a webhook, kept once sent as a delivery log.
+type WebhookDelivery struct {
+	ID            uint           `gorm:"column:id;primary_key" json:"id"`
+	CreatedAt     time.Time      `gorm:"column:created_at;DEFAULT:CURRENT_TIMESTAMP" json:"created_at"`
+	WebhookID     string         `gorm:"column:webhook_id;type:varchar(32);index:idx_webhook_deliveries_webhook_id" json:"-"`
+	Event         WebhookEvent   `gorm:"column:event;type:varchar(32)" json:"event"`
+	Payload       string         `gorm:"column:payload;type:text" json:"-"`
+	Status        DeliveryStatus `gorm:"column:status;type:varchar(16);index:idx_webhook_deliveries_status_next_attempt_at" json:"status"`
+	Attempts      int64          `gorm:"column:attempts" json:"attempts"`
+	NextAttemptAt time.Time      `gorm:"column:next_attempt_at;index:idx_webhook_deliveries_status_next_attempt_at" json:"next_attempt_at"`
+	LastAttemptAt *time.Time     `gorm:"column:last_attempt_at" json:"last_attempt_at,omitempty"`
+	ResponseCode  int            `gorm:"column:response_code" json:"response_code,omitempty"`
+	Error         string         `gorm:"column:error;type:text" json:"error,omitempty"`
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsInternalIP checks if the address is not reachable from Internet (loopback, private, link-local, unspecified...),
+// webhooks can't be delivered to these addresses.
+func IsInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+// ValidateWebhookURL checks that the URL is an HTTP(S) URL that only resolves to public addresses, internal addresses are
+// accepted when allowLocal is true.
+func ValidateWebhookURL(rawURL string, allowLocal bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.Errorf("invalid webhook url %s", rawURL)
+	}
+	if allowLocal {
+		return nil
+	}
+
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		if strings.EqualFold(u.Hostname(), "localhost") || strings.HasSuffix(strings.ToLower(u.Hostname()), ".localhost") {
+			return errors.Errorf("webhook url %s targets an internal address", rawURL)
+		}
+		if ips, err = net.LookupIP(u.Hostname()); err != nil {
+			return errors.Errorf("can't resolve webhook url host %s", u.Hostname())
+		}
+	}
+	for _, ip := range ips {
+		if IsInternalIP(ip) {
+			return errors.Errorf("webhook url %s targets an internal address", rawURL)
+		}
+	}
+	return nil
+}
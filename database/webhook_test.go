@@ -0,0 +1,43 @@
+package database_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::", "100.64.0.1", "::ffff:127.0.0.1"} {
+		assert.True(t, database.IsInternalIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"203.0.113.10", "8.8.8.8", "2001:4860:4860::8888"} {
+		assert.False(t, database.IsInternalIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hook",
+		"http://[::1]/hook",
+		"http://localhost/hook",
+		"http://api.localhost/hook",
+		"ftp://203.0.113.10/hook",
+		"https:///hook",
+	} {
+		assert.Error(t, database.ValidateWebhookURL(u, false), u)
+	}
+	assert.NoError(t, database.ValidateWebhookURL("https://203.0.113.10/hook", false))
+
+	// Local receivers are accepted when allowed
+	for _, u := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook"} {
+		assert.NoError(t, database.ValidateWebhookURL(u, true), u)
+	}
+	for _, u := range []string{"ftp://127.0.0.1/hook", "https:///hook"} {
+		assert.Error(t, database.ValidateWebhookURL(u, true), u)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -22,6 +24,12 @@ func main() {
 			Usage:   "Postgres database URL",
 			EnvVars: []string{"STARGAZER_PG_URL", "DATABASE_URL"},
 		},
+		&cli.StringFlag{
+			Name:    "mgo-uri",
+			Value:   "mongodb://localhost:27017",
+			Usage:   "Mongo database URI",
+			EnvVars: []string{"STARGAZER_MGO_URI"},
+		},
 		&cli.StringFlag{
 			Name:    "log-level",
 			Value:   "info",
@@ -34,12 +42,52 @@ func main() {
 			Usage:   "Set the path for main repository.",
 			EnvVars: []string{"STARGAZER_MAIN_REPOSITORY"},
 		},
+		&cli.StringFlag{
+			Name:    "title",
+			Value:   "Stargazer",
+			Usage:   "Set the title displayed in pages for main repository.",
+			EnvVars: []string{"STARGAZER_TITLE"},
+		},
+		&cli.StringFlag{
+			Name:    "tenants-file",
+			Usage:   "Set the path of a JSON file that declares additional tenants with their own main repository.",
+			EnvVars: []string{"STARGAZER_TENANTS_FILE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "task-repository-exclusions",
+			Value:   cli.NewStringSlice("richardlt/stargazer"),
+			Usage:   "Set the repositories that you want to exclude from computing.",
+			EnvVars: []string{"STARGAZER_TASK_REPOSITORY_EXCLUSIONS"},
+		},
+		&cli.Int64Flag{
+			Name:    "max-entries-count",
+			Value:   100,
+			Usage:   "Set the max count of entries to store in database.",
+			EnvVars: []string{"STARGAZER_MAX_ENTRIES_COUNT"},
+		},
 		&cli.Int64Flag{
 			Name:    "task-repository-org-contributors-to-check",
 			Value:   10,
 			Usage:   "Set the count of organization contributors to includes when checking for start on main repository.",
 			EnvVars: []string{"STARGAZER_TASK_REPOSITORY_ORG_CONTRIBUTORS_TO_CHECK"},
 		},
+		&cli.StringFlag{
+			Name:    "timezone",
+			Value:   "UTC",
+			Usage:   "Set the default IANA timezone used to compute stats per days.",
+			EnvVars: []string{"STARGAZER_TIMEZONE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "eligibility-strategies",
+			Value:   cli.NewStringSlice("star"),
+			Usage:   "Set the strategies that make a repository eligible for stats computing [star file topic badge].",
+			EnvVars: []string{"STARGAZER_ELIGIBILITY_STRATEGIES"},
+		},
+		&cli.BoolFlag{
+			Name:    "webhook-allow-local-addresses",
+			Usage:   "Allow webhooks to local addresses (loopback, private, link-local), to test deliveries with a local receiver.",
+			EnvVars: []string{"STARGAZER_WEBHOOK_ALLOW_LOCAL_ADDRESSES"},
+		},
 	}
 
 	app.Commands = []*cli.Command{
@@ -52,12 +100,6 @@ func main() {
 					Usage:   "Github api token",
 					EnvVars: []string{"STARGAZER_GH_TOKEN"},
 				},
-				&cli.StringFlag{
-					Name:    "mgo-uri",
-					Value:   "mongodb://localhost:27017",
-					Usage:   "Mongo database URI",
-					EnvVars: []string{"STARGAZER_MGO_URI"},
-				},
 				&cli.Int64Flag{
 					Name:    "user-expiration-delay",
 					Value:   3600,
@@ -82,11 +124,64 @@ func main() {
 					Usage:   "Set the maximum stargazer pages to load for a repository.",
 					EnvVars: []string{"STARGAZER_TASK_REPOSITORY_MAX_STARGAZER_PAGES"},
 				},
-				&cli.StringSliceFlag{
-					Name:    "task-repository-exclusions",
-					Value:   cli.NewStringSlice("richardlt/stargazer"),
-					Usage:   "Set the repositories that you want to exclude from computing.",
-					EnvVars: []string{"STARGAZER_TASK_REPOSITORY_EXCLUSIONS"},
+				&cli.BoolFlag{
+					Name:    "task-repository-enrich-users",
+					Usage:   "Enable loading of user profiles for recent stargazers of task repositories.",
+					EnvVars: []string{"STARGAZER_TASK_REPOSITORY_ENRICH_USERS"},
+				},
+				&cli.Int64Flag{
+					Name:    "task-repository-enrich-users-count",
+					Value:   100,
+					Usage:   "Set the count of recent stargazers to load user profiles for.",
+					EnvVars: []string{"STARGAZER_TASK_REPOSITORY_ENRICH_USERS_COUNT"},
+				},
+				&cli.Float64Flag{
+					Name:    "task-repository-enrich-users-rate-limit-share",
+					Value:   0.5,
+					Usage:   "Stop loading user profiles once this share of the Github rate limit was used (from 0 to 1).",
+					EnvVars: []string{"STARGAZER_TASK_REPOSITORY_ENRICH_USERS_RATE_LIMIT_SHARE"},
+				},
+				&cli.Int64Flag{
+					Name:    "stats-spike-window-days",
+					Value:   30,
+					Usage:   "Set the count of previous days used to detect a spike of stars.",
+					EnvVars: []string{"STARGAZER_STATS_SPIKE_WINDOW_DAYS"},
+				},
+				&cli.Float64Flag{
+					Name:    "stats-spike-threshold",
+					Value:   3,
+					Usage:   "Set how many standard deviations above the mean a day should be to be a spike of stars.",
+					EnvVars: []string{"STARGAZER_STATS_SPIKE_THRESHOLD"},
+				},
+				&cli.Int64Flag{
+					Name:    "stats-release-window-days",
+					Value:   7,
+					Usage:   "Set the count of days after a release to count the stars it brought.",
+					EnvVars: []string{"STARGAZER_STATS_RELEASE_WINDOW_DAYS"},
+				},
+				&cli.Int64Flag{
+					Name:    "stats-snapshots-retention-days",
+					Value:   365,
+					Usage:   "Set how many days stats snapshots are kept (0 means no expiration).",
+					EnvVars: []string{"STARGAZER_STATS_SNAPSHOTS_RETENTION_DAYS"},
+				},
+				&cli.Int64Flag{
+					Name:    "webhook-delivery-scan-delay",
+					Value:   10,
+					Usage:   "Set the delay in seconds between two scans of webhook deliveries to send.",
+					EnvVars: []string{"STARGAZER_WEBHOOK_DELIVERY_SCAN_DELAY"},
+				},
+				&cli.Int64Flag{
+					Name:    "webhook-max-attempts",
+					Value:   8,
+					Usage:   "Set the max count of attempts for a webhook delivery before giving up.",
+					EnvVars: []string{"STARGAZER_WEBHOOK_MAX_ATTEMPTS"},
+				},
+				&cli.Int64Flag{
+					Name:    "webhook-retry-delay",
+					Value:   60,
+					Usage:   "Set the delay in seconds before retrying a failed webhook delivery, doubled after each attempt.",
+					EnvVars: []string{"STARGAZER_WEBHOOK_RETRY_DELAY"},
 				},
 			),
 			Action: func(c *cli.Context) error {
@@ -95,20 +190,41 @@ func main() {
 					return errors.Wrap(err, "invalid given log level")
 				}
 
+				if _, err := time.LoadLocation(c.String("timezone")); err != nil {
+					return errors.Wrap(err, "invalid given timezone")
+				}
+
+				tenants, err := loadTenants(c)
+				if err != nil {
+					return err
+				}
+
 				return crawler.Start(config.Crawler{
 					Common: config.Common{
 						LogLevel:                             level,
 						DatabaseURL:                          c.String("pg-url"),
-						MainRepository:                       c.String("main-repository"),
+						MgoURI:                               c.String("mgo-uri"),
+						Tenants:                              tenants,
 						TaskRepositoryOrgContributorsToCheck: c.Int64("task-repository-org-contributors-to-check"),
+						EligibilityStrategies:                c.StringSlice("eligibility-strategies"),
+						Timezone:                             c.String("timezone"),
+						WebhookAllowLocalAddresses:           c.Bool("webhook-allow-local-addresses"),
 					},
-					MgoURI:                          c.String("mgo-uri"),
 					GHToken:                         c.String("gh-token"),
 					UserExpirationDelay:             c.Int64("user-expiration-delay"),
 					MainRepositoryScanDelay:         c.Int64("main-repository-scan-delay"),
 					TaskRepositoryScanDelay:         c.Int64("task-repository-scan-delay"),
 					TaskRepositoryMaxStargazerPages: c.Int64("task-repository-max-stargazer-pages"),
-					TaskRepositoryExclusions:        c.StringSlice("task-repository-exclusions"),
+					TaskRepositoryEnrichUsers:       c.Bool("task-repository-enrich-users"),
+					TaskRepositoryEnrichUsersCount:  c.Int64("task-repository-enrich-users-count"),
+					TaskRepositoryEnrichUsersShare:  c.Float64("task-repository-enrich-users-rate-limit-share"),
+					StatsSpikeWindowDays:            c.Int64("stats-spike-window-days"),
+					StatsSpikeThreshold:             c.Float64("stats-spike-threshold"),
+					StatsReleaseWindowDays:          c.Int64("stats-release-window-days"),
+					StatsSnapshotsRetentionDays:     c.Int64("stats-snapshots-retention-days"),
+					WebhookDeliveryScanDelay:        c.Int64("webhook-delivery-scan-delay"),
+					WebhookMaxAttempts:              c.Int64("webhook-max-attempts"),
+					WebhookRetryDelay:               c.Int64("webhook-retry-delay"),
 				})
 			},
 		},
@@ -127,11 +243,16 @@ func main() {
 					Usage:   "Set the delay for stats regenaration in seconds.",
 					EnvVars: []string{"STARGAZER_REGENERATE_DELAY"},
 				},
-				&cli.Int64Flag{
-					Name:    "max-entries-count",
-					Value:   100,
-					Usage:   "Set the max count of entries to store in database.",
-					EnvVars: []string{"STARGAZER_MAX_ENTRIES_COUNT"},
+				&cli.StringSliceFlag{
+					Name:    "api-cors-origins",
+					Value:   cli.NewStringSlice("*"),
+					Usage:   "Set the origins allowed to call the API from a browser.",
+					EnvVars: []string{"STARGAZER_API_CORS_ORIGINS"},
+				},
+				&cli.StringSliceFlag{
+					Name:    "trusted-proxies",
+					Usage:   "Set the IPs or CIDRs of the reverse proxies allowed to give the client's scheme and host with X-Forwarded-* headers.",
+					EnvVars: []string{"STARGAZER_TRUSTED_PROXIES"},
 				},
 			),
 			Action: func(c *cli.Context) error {
@@ -140,16 +261,30 @@ func main() {
 					return errors.WithStack(err)
 				}
 
+				if _, err := time.LoadLocation(c.String("timezone")); err != nil {
+					return errors.Wrap(err, "invalid given timezone")
+				}
+
+				tenants, err := loadTenants(c)
+				if err != nil {
+					return err
+				}
+
 				return web.Start(config.Web{
 					Common: config.Common{
 						LogLevel:                             level,
 						DatabaseURL:                          c.String("pg-url"),
-						MainRepository:                       c.String("main-repository"),
+						MgoURI:                               c.String("mgo-uri"),
+						Tenants:                              tenants,
 						TaskRepositoryOrgContributorsToCheck: c.Int64("task-repository-org-contributors-to-check"),
+						EligibilityStrategies:                c.StringSlice("eligibility-strategies"),
+						Timezone:                             c.String("timezone"),
+						WebhookAllowLocalAddresses:           c.Bool("webhook-allow-local-addresses"),
 					},
 					Port:            c.Int64("port"),
 					RegenerateDelay: c.Int64("regenerate-delay"),
-					MaxEntriesCount: c.Int64("max-entries-count"),
+					APICORSOrigins:  c.StringSlice("api-cors-origins"),
+					TrustedProxies:  c.StringSlice("trusted-proxies"),
 				})
 			},
 		},
@@ -159,3 +294,24 @@ func main() {
 		logrus.Errorf("%+v", err)
 	}
 }
+
+// loadTenants returns the default tenant built from flags followed by tenants from the tenants file if given.
+func loadTenants(c *cli.Context) ([]config.Tenant, error) {
+	tenants := []config.Tenant{{
+		Name:                     config.DefaultTenant,
+		Title:                    c.String("title"),
+		MainRepository:           c.String("main-repository"),
+		TaskRepositoryExclusions: c.StringSlice("task-repository-exclusions"),
+		MaxEntriesCount:          c.Int64("max-entries-count"),
+	}}
+
+	if c.String("tenants-file") != "" {
+		ts, err := config.LoadTenants(c.String("tenants-file"))
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, ts...)
+	}
+
+	return tenants, nil
+}
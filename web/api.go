@@ -0,0 +1,350 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiRepository struct {
+	Repository      string          `json:"repository"`
+	Status          database.Status `json:"status"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastRequestedAt time.Time       `json:"last_requested_at"`
+	LastGeneratedAt *time.Time      `json:"last_generated_at,omitempty"`
+	Timezone        string          `json:"timezone"`
+	Stats           database.Stats  `json:"stats"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Errorf("%+v", errors.WithStack(err))
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, code int, err error) {
+	if code == http.StatusInternalServerError {
+		logrus.Errorf("%+v", err)
+		writeJSON(w, code, apiError{Error: http.StatusText(code)})
+		return
+	}
+	writeJSON(w, code, apiError{Error: err.Error()})
+}
+
+// corsMiddleware allows API calls from browsers on given origins, all origins are allowed for "*".
+func corsMiddleware(origins []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if origin := r.Header.Get("Origin"); origin != "" {
+				for _, o := range origins {
+					if o == "*" || strings.EqualFold(o, origin) {
+						w.Header().Set("Access-Control-Allow-Origin", o)
+						w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Add("Vary", "Origin")
+						break
+					}
+				}
+			}
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// repositoryAPIHandler returns the entry with its stats for a repository, 202 is returned while stats are generated.
+// A POST request stats generation like opening the repository page.
+func (s *Server) repositoryAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+		tenant := s.tenant(r)
+
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		e, err := s.db.Get(tenant.Name, repoPath)
+		if err != nil && errors.Cause(err) != gorm.ErrRecordNotFound {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			var status int
+			e, status, err = s.requestEntry(tenant, repoPath, e)
+			if err != nil {
+				writeJSONError(w, status, err)
+				return
+			}
+		}
+		if e == nil {
+			writeJSONError(w, http.StatusNotFound, errors.Errorf("no stats for repository %s", repoPath))
+			return
+		}
+
+		stats := statsInLocation(e.Stats, e.LastGeneratedAt, loc)
+		res := apiRepository{
+			Repository:      e.Repository,
+			Status:          e.Status,
+			CreatedAt:       e.CreatedAt,
+			LastRequestedAt: e.LastRequestedAt,
+			Timezone:        s.statsTimezone(stats),
+			Stats:           stats,
+		}
+		if e.HasStats() {
+			res.LastGeneratedAt = &e.LastGeneratedAt
+		}
+
+		code := http.StatusOK
+		if e.Status != database.StatusGenerated {
+			code = http.StatusAccepted
+		}
+		writeJSON(w, code, res)
+	}
+}
+
+func (s *Server) snapshotsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+
+		e, err := s.db.Get(s.tenant(r).Name, repoPath)
+		if err != nil {
+			if errors.Cause(err) == gorm.ErrRecordNotFound {
+				writeJSONError(w, http.StatusNotFound, errors.Errorf("no stats for repository %s", repoPath))
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		at, diff := r.URL.Query().Get("at"), r.URL.Query().Get("diff")
+		if at == "" && diff == "" {
+			ss, err := s.db.GetSnapshots(e.ID)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, ss)
+			return
+		}
+
+		snapshot, statsDiff, status, err := s.snapshotAndDiff(*e, at, diff)
+		if err != nil {
+			writeJSONError(w, status, err)
+			return
+		}
+		if statsDiff != nil {
+			writeJSON(w, http.StatusOK, statsDiff)
+			return
+		}
+		writeJSON(w, http.StatusOK, snapshot)
+	}
+}
+
+type apiWebhookRequest struct {
+	URL    string                  `json:"url"`
+	Secret string                  `json:"secret"`
+	Events []database.WebhookEvent `json:"events"`
+}
+
+type apiWebhook struct {
+	database.Webhook
+	Repository string                     `json:"repository"`
+	Deliveries []database.WebhookDelivery `json:"deliveries,omitempty"`
+}
+
+// validateWebhookRequest checks the URL and events of a webhook registration, all events are subscribed when none given.
+func validateWebhookRequest(req *apiWebhookRequest, allowLocal bool) error {
+	if err := database.ValidateWebhookURL(req.URL, allowLocal); err != nil {
+		return err
+	}
+	if req.Secret == "" {
+		return errors.New("missing webhook secret")
+	}
+	if len(req.Events) == 0 {
+		req.Events = database.WebhookEvents
+	}
+	for _, e := range req.Events {
+		if !database.WebhookFilter(database.WebhookEvents).Accepts(e) {
+			return errors.Errorf("invalid webhook event %s", e)
+		}
+	}
+	return nil
+}
+
+// authorizedEntry returns the entry for the request's repository if the request gives the entry's token, an error
+// response is written otherwise.
+func (s *Server) authorizedEntry(w http.ResponseWriter, r *http.Request) (*database.Entry, bool) {
+	vars := mux.Vars(r)
+	repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		writeJSONError(w, http.StatusUnauthorized, errors.New("missing repository token"))
+		return nil, false
+	}
+
+	e, err := s.db.Get(s.tenant(r).Name, repoPath)
+	if err != nil {
+		if errors.Cause(err) == gorm.ErrRecordNotFound {
+			writeJSONError(w, http.StatusNotFound, errors.Errorf("no stats for repository %s", repoPath))
+			return nil, false
+		}
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if e.Token == "" || subtle.ConstantTimeCompare([]byte(e.Token), []byte(token)) != 1 {
+		writeJSONError(w, http.StatusForbidden, errors.Errorf("invalid token for repository %s", repoPath))
+		return nil, false
+	}
+
+	return e, true
+}
+
+type apiToken struct {
+	Repository string `json:"repository"`
+	Token      string `json:"token"`
+}
+
+// tokenAPIHandler returns the token of a repository, a POST request replaces it with a new one. The caller gives a
+// Github token of a user that is admin of the repository to prove that they own it.
+func (s *Server) tokenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+
+		ghToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ghToken == "" || ghToken == r.Header.Get("Authorization") {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("missing Github token"))
+			return
+		}
+
+		e, err := s.db.Get(s.tenant(r).Name, repoPath)
+		if err != nil {
+			if errors.Cause(err) == gorm.ErrRecordNotFound {
+				writeJSONError(w, http.StatusNotFound, errors.Errorf("no stats for repository %s", repoPath))
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		repo, err := s.githubClient(ghToken).GetRepository(repoPath)
+		if err != nil {
+			logrus.Debugf("Can't get repository %s with given Github token: %v", repoPath, err)
+			writeJSONError(w, http.StatusForbidden, errors.Errorf("can't check permissions on repository %s with given Github token", repoPath))
+			return
+		}
+		if !repo.Permissions.Admin {
+			writeJSONError(w, http.StatusForbidden, errors.Errorf("given Github token is not admin of repository %s", repoPath))
+			return
+		}
+
+		if r.Method == http.MethodPost || e.Token == "" {
+			token, err := database.NewToken()
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if err := s.db.UpdateToken(e, token); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		writeJSON(w, http.StatusOK, apiToken{Repository: e.Repository, Token: e.Token})
+	}
+}
+
+// webhooksAPIHandler registers a webhook for a repository with stats, the repository's token is required. The returned
+// id is needed to view the webhook's deliveries or delete it.
+func (s *Server) webhooksAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, ok := s.authorizedEntry(w, r)
+		if !ok {
+			return
+		}
+
+		var req apiWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, errors.New("invalid webhook"))
+			return
+		}
+		if err := validateWebhookRequest(&req, s.webhookAllowLocalAddresses); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		id, err := database.NewToken()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		wh := database.Webhook{ID: id, EntryID: e.ID, URL: req.URL, Secret: req.Secret, Events: req.Events}
+		if err := s.db.CreateWebhook(&wh); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, apiWebhook{Webhook: wh, Repository: e.Repository})
+	}
+}
+
+// webhookAPIHandler returns a webhook with its last deliveries, or deletes it. The repository's token is required.
+func (s *Server) webhookAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, ok := s.authorizedEntry(w, r)
+		if !ok {
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		wh, err := s.db.GetWebhook(id)
+		if err != nil && errors.Cause(err) != gorm.ErrRecordNotFound {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if wh == nil || wh.EntryID != e.ID {
+			writeJSONError(w, http.StatusNotFound, errors.Errorf("no webhook %s for repository %s", id, e.Repository))
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			if err := s.db.DeleteWebhook(wh.ID); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		ds, err := s.db.GetDeliveries(wh.ID, 50)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiWebhook{Webhook: *wh, Repository: e.Repository, Deliveries: ds})
+	}
+}
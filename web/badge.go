@@ -0,0 +1,167 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+const (
+	badgeColorStars   = "#007ec6"
+	badgeColorUnknown = "#9f9f9f"
+)
+
+// formatCount returns a short representation of a count (ex: 1.2k, 12k, 3.4M).
+func formatCount(count int64) string {
+	switch {
+	case count >= 10000000:
+		return fmt.Sprintf("%dM", count/1000000)
+	case count >= 1000000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(count/100000)/10), ".0") + "M"
+	case count >= 10000:
+		return fmt.Sprintf("%dk", count/1000)
+	case count >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(count/100)/10), ".0") + "k"
+	}
+	return strconv.FormatInt(count, 10)
+}
+
+// textWidth approximates the width in pixels of a text written with Verdana 11px.
+func textWidth(text string) int {
+	var width float64
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("iljI.,:;!|' ", r):
+			width += 3.5
+		case strings.ContainsRune("mwMW", r):
+			width += 10
+		case r >= 'A' && r <= 'Z':
+			width += 7.5
+		default:
+			width += 6.5
+		}
+	}
+	return int(width + 0.5)
+}
+
+// renderBadge returns a shields like SVG badge.
+func renderBadge(label, value, color string) []byte {
+	labelWidth, valueWidth := textWidth(label)+10, textWidth(value)+10
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, value)
+	fmt.Fprintf(&buf, `<title>%s: %s</title>`, label, value)
+	buf.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&buf, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&buf, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, labelWidth, valueWidth, color, width)
+	buf.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, t := range []struct {
+		x    int
+		text string
+	}{{labelWidth / 2, label}, {labelWidth + valueWidth/2, value}} {
+		fmt.Fprintf(&buf, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, t.x, t.text, t.x, t.text)
+	}
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes()
+}
+
+// renderSparkline returns a SVG line chart of given measures without axes.
+func renderSparkline(ms []database.Measure, width, height int) []byte {
+	var max int64
+	for _, m := range ms {
+		if m.Count > max {
+			max = m.Count
+		}
+	}
+
+	points := make([]string, len(ms))
+	for i, m := range ms {
+		x := 0.0
+		if len(ms) > 1 {
+			x = float64(i) * float64(width) / float64(len(ms)-1)
+		}
+		y := float64(height - 1)
+		if max > 0 {
+			y = 1 + float64(height-2)*(1-float64(m.Count)/float64(max))
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="stars per day">`, width, height, width, height)
+	if len(points) > 0 {
+		fmt.Fprintf(&buf, `<polygon points="0,%d %s %d,%d" fill="#1DBC60" fill-opacity=".2"/>`, height, strings.Join(points, " "), width, height)
+		fmt.Fprintf(&buf, `<polyline points="%s" fill="none" stroke="#1DBC60" stroke-width="1.5"/>`, strings.Join(points, " "))
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// embeddedEntry returns the entry of the repository for an embedded badge or chart if it exists, it is requested again
+// if its stats expired.
+func (s *Server) embeddedEntry(r *http.Request) *database.Entry {
+	vars := mux.Vars(r)
+	repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+	tenant := s.tenant(r)
+
+	e, err := s.db.Get(tenant.Name, repoPath)
+	if err != nil {
+		if errors.Cause(err) != gorm.ErrRecordNotFound {
+			logrus.Errorf("%+v", err)
+		}
+		return nil
+	}
+	if _, _, err := s.requestEntry(tenant, repoPath, e); err != nil {
+		logrus.Errorf("%+v", err)
+	}
+	return e
+}
+
+func writeSVG(w http.ResponseWriter, svg []byte, maxAge time.Duration) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds())))
+	if _, err := w.Write(svg); err != nil {
+		logrus.Errorf("%+v", errors.WithStack(err))
+	}
+}
+
+func (s *Server) badgeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e := s.embeddedEntry(r)
+		if e == nil || !e.HasStats() {
+			writeSVG(w, renderBadge("stars", "unknown", badgeColorUnknown), 5*time.Minute)
+			return
+		}
+
+		value := formatCount(e.Stats.CountStars)
+		if e.Stats.Trend != nil && e.Stats.Trend.Last7Days > 0 {
+			value += fmt.Sprintf(" | +%d this week", e.Stats.Trend.Last7Days)
+		}
+		writeSVG(w, renderBadge("stars", value, badgeColorStars), time.Hour)
+	}
+}
+
+func (s *Server) sparklineHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e := s.embeddedEntry(r)
+		if e == nil {
+			writeSVG(w, renderSparkline(nil, 120, 20), 5*time.Minute)
+			return
+		}
+		writeSVG(w, renderSparkline(e.Stats.PerDays, 120, 20), time.Hour)
+	}
+}
@@ -0,0 +1,39 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func Test_formatCount(t *testing.T) {
+	assert.Equal(t, "999", formatCount(999))
+	assert.Equal(t, "1k", formatCount(1000))
+	assert.Equal(t, "1.2k", formatCount(1290))
+	assert.Equal(t, "12k", formatCount(12900))
+	assert.Equal(t, "1.2M", formatCount(1290000))
+	assert.Equal(t, "12M", formatCount(12900000))
+}
+
+func Test_renderBadge(t *testing.T) {
+	svg := string(renderBadge("stars", "1.2k | +12 this week", badgeColorStars))
+	assert.Contains(t, svg, `aria-label="stars: 1.2k | +12 this week"`)
+	assert.Contains(t, svg, `fill="#007ec6"`)
+
+	assert.Contains(t, string(renderBadge("stars", "<none>", badgeColorUnknown)), "&lt;none&gt;")
+}
+
+func Test_renderSparkline(t *testing.T) {
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	svg := string(renderSparkline([]database.Measure{
+		{Date: day, Count: 0},
+		{Date: day.AddDate(0, 0, 1), Count: 4},
+		{Date: day.AddDate(0, 0, 2), Count: 2},
+	}, 100, 20))
+	assert.Contains(t, svg, `<polyline points="0.0,19.0 50.0,1.0 100.0,10.0"`)
+
+	assert.NotContains(t, string(renderSparkline(nil, 100, 20)), "polyline")
+}
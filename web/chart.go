@@ -0,0 +1,304 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+// canvas is a drawing surface for charts, coordinates are in pixels from the top left corner.
+type canvas interface {
+	rect(x, y, w, h float64, c color.RGBA)
+	line(x1, y1, x2, y2, width float64, c color.RGBA)
+	// text writes a text with its top at y, anchor is one of start, middle or end.
+	text(x, y float64, s string, c color.RGBA, anchor string)
+	encode(w io.Writer) error
+}
+
+// textSize returns the size of a text written with the bitmap font at given scale.
+func textSize(s string, scale int) (float64, float64) {
+	return float64(len([]rune(s))*(glyphWidth+1)*scale - scale), float64(glyphHeight * scale)
+}
+
+func anchorOffset(s string, scale int, anchor string) float64 {
+	w, _ := textSize(s, scale)
+	switch anchor {
+	case "middle":
+		return -w / 2
+	case "end":
+		return -w
+	}
+	return 0
+}
+
+type svgCanvas struct {
+	buf   bytes.Buffer
+	scale int
+}
+
+func newSVGCanvas(width, height, scale int) *svgCanvas {
+	c := &svgCanvas{scale: scale}
+	fmt.Fprintf(&c.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	return c
+}
+
+func svgColor(c color.RGBA) string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+
+func (c *svgCanvas) rect(x, y, w, h float64, col color.RGBA) {
+	fmt.Fprintf(&c.buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x, y, w, h, svgColor(col))
+}
+
+func (c *svgCanvas) line(x1, y1, x2, y2, width float64, col color.RGBA) {
+	fmt.Fprintf(&c.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%.1f"/>`, x1, y1, x2, y2, svgColor(col), width)
+}
+
+func (c *svgCanvas) text(x, y float64, s string, col color.RGBA, anchor string) {
+	_, h := textSize(s, c.scale)
+	fmt.Fprintf(&c.buf, `<text x="%.1f" y="%.1f" fill="%s" font-family="monospace" font-size="%d" text-anchor="%s">%s</text>`,
+		x, y+h, svgColor(col), 10*c.scale, anchor, html.EscapeString(s))
+}
+
+func (c *svgCanvas) encode(w io.Writer) error {
+	c.buf.WriteString(`</svg>`)
+	_, err := w.Write(c.buf.Bytes())
+	return errors.WithStack(err)
+}
+
+type pngCanvas struct {
+	img   *image.RGBA
+	scale int
+}
+
+func newPNGCanvas(width, height, scale int) *pngCanvas {
+	return &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height)), scale: scale}
+}
+
+func (c *pngCanvas) rect(x, y, w, h float64, col color.RGBA) {
+	for j := int(math.Round(y)); j < int(math.Round(y+h)); j++ {
+		for i := int(math.Round(x)); i < int(math.Round(x+w)); i++ {
+			c.img.SetRGBA(i, j, col)
+		}
+	}
+}
+
+func (c *pngCanvas) line(x1, y1, x2, y2, width float64, col color.RGBA) {
+	steps := math.Max(math.Abs(x2-x1), math.Abs(y2-y1))
+	if steps < 1 {
+		steps = 1
+	}
+	for i := 0.0; i <= steps; i++ {
+		x, y := x1+(x2-x1)*i/steps, y1+(y2-y1)*i/steps
+		c.rect(x-width/2, y-width/2, math.Max(width, 1), math.Max(width, 1), col)
+	}
+}
+
+func (c *pngCanvas) text(x, y float64, s string, col color.RGBA, anchor string) {
+	x += anchorOffset(s, c.scale, anchor)
+	for _, r := range s {
+		g := glyph(r)
+		for j := range g {
+			for i, p := range g[j] {
+				if p == '#' {
+					c.rect(x+float64(i*c.scale), y+float64(j*c.scale), float64(c.scale), float64(c.scale), col)
+				}
+			}
+		}
+		x += float64((glyphWidth + 1) * c.scale)
+	}
+}
+
+func (c *pngCanvas) encode(w io.Writer) error {
+	return errors.WithStack(png.Encode(w, c.img))
+}
+
+type chartTheme struct {
+	Background color.RGBA
+	Text       color.RGBA
+	Grid       color.RGBA
+	Line       color.RGBA
+	Bar        color.RGBA
+}
+
+var chartThemes = map[string]chartTheme{
+	"light": {
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Text:       color.RGBA{0x33, 0x33, 0x33, 0xff},
+		Grid:       color.RGBA{0xe5, 0xe5, 0xe5, 0xff},
+		Line:       color.RGBA{0x3b, 0xab, 0xfd, 0xff},
+		Bar:        color.RGBA{0x1d, 0xbc, 0x60, 0xff},
+	},
+	"dark": {
+		Background: color.RGBA{0x0d, 0x11, 0x17, 0xff},
+		Text:       color.RGBA{0xc9, 0xd1, 0xd9, 0xff},
+		Grid:       color.RGBA{0x30, 0x36, 0x3d, 0xff},
+		Line:       color.RGBA{0x58, 0xa6, 0xff, 0xff},
+		Bar:        color.RGBA{0x3f, 0xb9, 0x50, 0xff},
+	},
+}
+
+// chartKinds gives for each chart kind its title, its series and if it is drawn with bars.
+var chartKinds = map[string]struct {
+	Title  string
+	Series func(s database.Stats) []database.Measure
+	Bars   bool
+}{
+	"evolution":  {"Stars evolution", func(s database.Stats) []database.Measure { return s.Evolution }, false},
+	"per-days":   {"Stars per days", func(s database.Stats) []database.Measure { return s.PerDays }, true},
+	"per-weeks":  {"Stars per weeks", func(s database.Stats) []database.Measure { return s.PerWeeks }, true},
+	"per-months": {"Stars per months", func(s database.Stats) []database.Measure { return s.PerMonths }, true},
+}
+
+// niceStep returns a round step between axis ticks to display about count ticks up to max.
+func niceStep(max int64, count int) int64 {
+	if max <= 0 {
+		return 1
+	}
+	raw := float64(max) / float64(count)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if step := m * magnitude; step >= raw {
+			return int64(math.Max(1, step))
+		}
+	}
+	return int64(10 * magnitude)
+}
+
+// drawChart draws a series on given canvas with a title, a count axis and dates of first and last measures.
+func drawChart(c canvas, width, height, scale int, title string, ms []database.Measure, bars bool, theme chartTheme) {
+	w, h := float64(width), float64(height)
+	_, lineHeight := textSize("0", scale)
+	c.rect(0, 0, w, h, theme.Background)
+	c.text(w/2, lineHeight/2, title, theme.Text, "middle")
+
+	var max int64
+	for _, m := range ms {
+		if m.Count > max {
+			max = m.Count
+		}
+	}
+	step := niceStep(max, 4)
+	top := step * ((max + step - 1) / step)
+	if top == 0 {
+		top = step
+	}
+
+	labelWidth, _ := textSize(formatCount(top), scale)
+	left, right := labelWidth+float64(4*scale), w-float64(4*scale)
+	upper, lower := 2*lineHeight, h-2*lineHeight
+	y := func(count int64) float64 { return lower - (lower-upper)*float64(count)/float64(top) }
+
+	for tick := int64(0); tick <= top; tick += step {
+		c.line(left, y(tick), right, y(tick), 1, theme.Grid)
+		c.text(left-float64(2*scale), y(tick)-lineHeight/2, formatCount(tick), theme.Text, "end")
+	}
+
+	if len(ms) == 0 {
+		c.text((left+right)/2, (upper+lower)/2-lineHeight/2, "No data", theme.Text, "middle")
+		return
+	}
+
+	c.text(left, lower+lineHeight/2, ms[0].Date.Format("2006-01-02"), theme.Text, "start")
+	if len(ms) > 1 {
+		c.text(right, lower+lineHeight/2, ms[len(ms)-1].Date.Format("2006-01-02"), theme.Text, "end")
+	}
+
+	if bars {
+		slot := (right - left) / float64(len(ms))
+		for i, m := range ms {
+			c.rect(left+float64(i)*slot+slot*0.1, y(m.Count), slot*0.8, lower-y(m.Count), theme.Bar)
+		}
+		return
+	}
+
+	// Line charts use dates for x axis as measures are not evenly spread
+	first, last := ms[0].Date, ms[len(ms)-1].Date
+	x := func(m database.Measure) float64 {
+		if !last.After(first) {
+			return left
+		}
+		return left + (right-left)*float64(m.Date.Sub(first))/float64(last.Sub(first))
+	}
+	for i := 1; i < len(ms); i++ {
+		c.line(x(ms[i-1]), y(ms[i-1].Count), x(ms[i]), y(ms[i].Count), float64(scale), theme.Line)
+	}
+}
+
+// queryInt returns an integer query param clamped between min and max, or the default value if not set or invalid.
+func queryInt(r *http.Request, key string, def, min, max int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return def
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func (s *Server) chartHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		kind, ok := chartKinds[vars["kind"]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		theme, ok := chartThemes[r.URL.Query().Get("theme")]
+		if r.URL.Query().Get("theme") == "" {
+			theme, ok = chartThemes["light"], true
+		}
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		width := queryInt(r, "width", 800, 200, 2000)
+		height := queryInt(r, "height", 400, 100, 1000)
+		scale := 1
+		if width >= 600 && height >= 300 {
+			scale = 2
+		}
+
+		e := s.embeddedEntry(r)
+		if e == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var c canvas
+		contentType := "image/svg+xml"
+		if vars["format"] == "png" {
+			c, contentType = newPNGCanvas(width, height, scale), "image/png"
+		} else {
+			c = newSVGCanvas(width, height, scale)
+		}
+		drawChart(c, width, height, scale, kind.Title+" - "+e.Repository, kind.Series(e.Stats), kind.Bars, theme)
+
+		var buf bytes.Buffer
+		if err := c.encode(&buf); err != nil {
+			logrus.Errorf("%+v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			logrus.Errorf("%+v", errors.WithStack(err))
+		}
+	}
+}
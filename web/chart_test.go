@@ -0,0 +1,45 @@
+package web
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func Test_niceStep(t *testing.T) {
+	assert.Equal(t, int64(1), niceStep(0, 4))
+	assert.Equal(t, int64(1), niceStep(3, 4))
+	assert.Equal(t, int64(5), niceStep(17, 4))
+	assert.Equal(t, int64(50), niceStep(130, 4))
+	assert.Equal(t, int64(5000), niceStep(12000, 4))
+}
+
+func Test_drawChart(t *testing.T) {
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := []database.Measure{{Date: day, Count: 3}, {Date: day.AddDate(0, 0, 1), Count: 0}, {Date: day.AddDate(0, 0, 2), Count: 7}}
+
+	svg := newSVGCanvas(400, 200, 1)
+	drawChart(svg, 400, 200, 1, "Stars per days", ms, true, chartThemes["light"])
+	var buf bytes.Buffer
+	require.NoError(t, svg.encode(&buf))
+	assert.Contains(t, buf.String(), ">Stars per days</text>")
+	assert.Contains(t, buf.String(), ">2021-01-03</text>")
+	assert.Contains(t, buf.String(), `fill="#1dbc60"`)
+
+	img := newPNGCanvas(400, 200, 1)
+	drawChart(img, 400, 200, 1, "Stars evolution", ms, false, chartThemes["dark"])
+	buf.Reset()
+	require.NoError(t, img.encode(&buf))
+	decoded, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 400, decoded.Bounds().Dx())
+	assert.Equal(t, 200, decoded.Bounds().Dy())
+	r, g, b, _ := decoded.At(0, 0).RGBA()
+	assert.Equal(t, []uint32{0x0d, 0x11, 0x17}, []uint32{r >> 8, g >> 8, b >> 8})
+}
@@ -0,0 +1,142 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+type exportStargazer struct {
+	Login     string    `json:"login"`
+	AvatarURL string    `json:"avatar_url"`
+	StarredAt time.Time `json:"starred_at"`
+	Page      int64     `json:"page"`
+}
+
+type exportDay struct {
+	Date         string `json:"date"`
+	Count        int64  `json:"count"`
+	Interpolated bool   `json:"interpolated"`
+}
+
+// exportWriter writes exported rows as CSV or NDJSON.
+type exportWriter interface {
+	header(columns ...string) error
+	row(v interface{}, values ...string) error
+	flush() error
+}
+
+type csvExportWriter struct{ w *csv.Writer }
+
+func (e csvExportWriter) header(columns ...string) error { return errors.WithStack(e.w.Write(columns)) }
+
+func (e csvExportWriter) row(_ interface{}, values ...string) error {
+	return errors.WithStack(e.w.Write(values))
+}
+
+func (e csvExportWriter) flush() error {
+	e.w.Flush()
+	return errors.WithStack(e.w.Error())
+}
+
+type ndjsonExportWriter struct{ e *json.Encoder }
+
+func (n ndjsonExportWriter) header(...string) error { return nil }
+
+func (n ndjsonExportWriter) row(v interface{}, _ ...string) error {
+	return errors.WithStack(n.e.Encode(v))
+}
+
+func (n ndjsonExportWriter) flush() error { return nil }
+
+// exportHandler exports stored stargazers or the daily series of a repository, the repository's token is required. Only
+// repositories with generated stats can be exported as they passed eligibility checks.
+func (s *Server) exportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+
+		data := r.URL.Query().Get("data")
+		if data == "" {
+			data = "stargazers"
+		}
+		if data != "stargazers" && data != "daily" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		e, ok := s.authorizedEntry(w, r)
+		if !ok {
+			return
+		}
+		if !e.HasStats() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var ew exportWriter
+		filename := fmt.Sprintf("%s-%s.%s", strings.ReplaceAll(repoPath, "/", "-"), data, vars["format"])
+		if vars["format"] == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			ew = csvExportWriter{csv.NewWriter(w)}
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			ew = ndjsonExportWriter{json.NewEncoder(w)}
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+		if err := s.export(ew, *e, data); err != nil {
+			logrus.Errorf("%+v", err)
+		}
+	}
+}
+
+func (s *Server) export(ew exportWriter, e database.Entry, data string) error {
+	if data == "daily" {
+		if err := ew.header("date", "count", "interpolated"); err != nil {
+			return err
+		}
+		interpolated := interpolatedDays(e.Stats.EvolutionBands)
+		for _, m := range database.DailySeries(e.Stats.CrawledEvolution()) {
+			d := exportDay{Date: m.Date.Format("2006-01-02"), Count: m.Count, Interpolated: interpolated[m.Date.Format("2006-01-02")]}
+			if err := ew.row(d, d.Date, strconv.FormatInt(d.Count, 10), strconv.FormatBool(d.Interpolated)); err != nil {
+				return err
+			}
+		}
+		return ew.flush()
+	}
+
+	if err := ew.header("login", "avatar_url", "starred_at", "page"); err != nil {
+		return err
+	}
+	if err := s.stargazers.Iterate(e.Tenant, e.Repository, func(st database.CrawledStargazer) error {
+		es := exportStargazer{Login: st.Login, AvatarURL: st.AvatarURL, StarredAt: st.StarredAt, Page: st.Page}
+		return ew.row(es, es.Login, es.AvatarURL, es.StarredAt.UTC().Format(time.RFC3339), strconv.FormatInt(es.Page, 10))
+	}); err != nil {
+		return err
+	}
+	return ew.flush()
+}
+
+// interpolatedDays returns the days with an estimated count of stars, that are the days between sampled pages with an
+// uncertain count and the day that ends such a gap.
+func interpolatedDays(bands []database.Band) map[string]bool {
+	days := make(map[string]bool)
+	for _, b := range bands {
+		if b.Lower != b.Upper {
+			days[b.Date.Format("2006-01-02")] = true
+			days[b.Date.AddDate(0, 0, 1).Format("2006-01-02")] = true
+		}
+	}
+	return days
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+func Test_export_daily(t *testing.T) {
+	e := database.Entry{Stats: database.Stats{Evolution: []database.Measure{
+		{Date: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), Count: 2},
+		{Date: time.Date(2021, 1, 3, 8, 0, 0, 0, time.UTC), Count: 5},
+		{Date: time.Date(2021, 1, 5, 12, 0, 0, 0, time.UTC), Count: 40000}, // Current count of stars
+	}}}
+	s := &Server{}
+
+	var buf bytes.Buffer
+	require.NoError(t, s.export(csvExportWriter{csv.NewWriter(&buf)}, e, "daily"))
+	assert.Equal(t, "date,count,interpolated\n2021-01-01,2,false\n2021-01-02,0,false\n2021-01-03,3,false\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, s.export(ndjsonExportWriter{json.NewEncoder(&buf)}, e, "daily"))
+	assert.Equal(t, `{"date":"2021-01-01","count":2,"interpolated":false}
+{"date":"2021-01-02","count":0,"interpolated":false}
+{"date":"2021-01-03","count":3,"interpolated":false}
+`, buf.String())
+
+	// Days between sampled pages are interpolated, until the day of the next loaded page
+	e = database.Entry{Stats: database.Stats{
+		Evolution: []database.Measure{
+			{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Count: 100},
+			{Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Count: 150},
+			{Date: time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), Count: 250},
+			{Date: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC), Count: 260},
+			{Date: time.Date(2021, 1, 5, 12, 0, 0, 0, time.UTC), Count: 300}, // Current count of stars
+		},
+		EvolutionBands: []database.Band{
+			{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Lower: 100, Upper: 100},
+			{Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Lower: 100, Upper: 200},
+			{Date: time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), Lower: 250, Upper: 250},
+			{Date: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC), Lower: 260, Upper: 260},
+			{Date: time.Date(2021, 1, 5, 12, 0, 0, 0, time.UTC), Lower: 300, Upper: 300},
+		},
+	}}
+	buf.Reset()
+	require.NoError(t, s.export(csvExportWriter{csv.NewWriter(&buf)}, e, "daily"))
+	assert.Equal(t, "date,count,interpolated\n2021-01-01,100,false\n2021-01-02,50,true\n2021-01-03,100,true\n2021-01-04,10,false\n", buf.String())
+}
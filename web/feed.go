@@ -0,0 +1,219 @@
+package web
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/richardlt/stargazer/database"
+)
+
+const feedPageSize = 50
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// parseTrustedProxies parses IPs or CIDRs of trusted reverse proxies.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var res []*net.IPNet
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy %s", p)
+			}
+			res = append(res, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted proxy %s", p)
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+// fromTrustedProxy checks if the request was sent by a trusted reverse proxy.
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range s.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseURL returns the scheme and host to reach the server. The tenant's host is used when configured, forwarded headers
+// are only used from trusted proxies.
+func (s *Server) baseURL(r *http.Request) string {
+	trusted := s.fromTrustedProxy(r)
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); trusted && (proto == "http" || proto == "https") {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); trusted && forwarded != "" {
+		host = forwarded
+	}
+	if t := s.tenant(r); len(t.Hosts) > 0 {
+		host = t.Hosts[0]
+	}
+
+	return scheme + "://" + host
+}
+
+// newAtomFeed returns a page of the feed of stargazers for a repository, pages are linked as a paged feed (RFC 5005).
+func newAtomFeed(feedURL, repo string, ss []database.CrawledStargazer, page, count int64, now time.Time) atomFeed {
+	lastPage := (count + feedPageSize - 1) / feedPageSize
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	pageURL := func(p int64) string {
+		if p == 1 {
+			return feedURL
+		}
+		return fmt.Sprintf("%s?page=%d", feedURL, p)
+	}
+
+	f := atomFeed{
+		ID:      feedURL,
+		Title:   fmt.Sprintf("Stargazers of %s", repo),
+		Updated: now.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: pageURL(page)},
+			{Rel: "alternate", Type: "text/html", Href: strings.TrimSuffix(feedURL, "/feed.atom")},
+			{Rel: "first", Href: pageURL(1)},
+			{Rel: "last", Href: pageURL(lastPage)},
+		},
+	}
+	if page > 1 {
+		f.Links = append(f.Links, atomLink{Rel: "previous", Href: pageURL(page - 1)})
+	}
+	if page < lastPage {
+		f.Links = append(f.Links, atomLink{Rel: "next", Href: pageURL(page + 1)})
+	}
+	if len(ss) > 0 {
+		f.Updated = ss[0].StarredAt.UTC().Format(time.RFC3339)
+	}
+
+	for _, s := range ss {
+		login := html.EscapeString(s.Login)
+		content := fmt.Sprintf(`<a href="https://github.com/%s">%s</a> starred %s on %s.`, login, login, html.EscapeString(repo), s.StarredAt.UTC().Format(time.RFC1123))
+		if s.AvatarURL != "" {
+			content = fmt.Sprintf(`<img src="%s" alt="%s" width="40" height="40" /> `, html.EscapeString(s.AvatarURL), login) + content
+		}
+		f.Entries = append(f.Entries, atomEntry{
+			ID:      fmt.Sprintf("urn:stargazer:%s:%s", repo, s.Login),
+			Title:   fmt.Sprintf("%s starred %s", s.Login, repo),
+			Updated: s.StarredAt.UTC().Format(time.RFC3339),
+			Author:  atomAuthor{Name: s.Login, URI: "https://github.com/" + s.Login},
+			Links:   []atomLink{{Rel: "alternate", Type: "text/html", Href: "https://github.com/" + s.Login}},
+			Content: atomContent{Type: "html", Body: content},
+		})
+	}
+
+	return f
+}
+
+// feedHandler returns an Atom feed of the latest stored stargazers for a repository with generated stats.
+func (s *Server) feedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		repoPath := strings.ToLower(vars["organization"] + "/" + vars["repository"])
+
+		page := int64(queryInt(r, "page", 1, 1, 1000000))
+
+		e, err := s.db.Get(s.tenant(r).Name, repoPath)
+		if err != nil {
+			if errors.Cause(err) != gorm.ErrRecordNotFound {
+				logrus.Errorf("%+v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !e.HasStats() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		ss, count, err := s.stargazers.GetLatest(e.Tenant, repoPath, (page-1)*feedPageSize, feedPageSize)
+		if err != nil {
+			logrus.Errorf("%+v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if page > 1 && len(ss) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		buf, err := xml.MarshalIndent(newAtomFeed(s.baseURL(r)+"/"+repoPath+"/feed.atom", repoPath, ss, page, count, time.Now()), "", "  ")
+		if err != nil {
+			logrus.Errorf("%+v", errors.WithStack(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		if _, err := w.Write(append([]byte(xml.Header), buf...)); err != nil {
+			logrus.Errorf("%+v", errors.WithStack(err))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/database"
+)
+
+func Test_newAtomFeed(t *testing.T) {
+	s := database.CrawledStargazer{
+		Login:     "alice",
+		AvatarURL: "https://avatars.example.com/alice",
+		StarredAt: time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC),
+	}
+
+	f := newAtomFeed("http://localhost/foo/bar/feed.atom", "foo/bar", []database.CrawledStargazer{s}, 2, 120, time.Now())
+
+	links := map[string]string{}
+	for _, l := range f.Links {
+		links[l.Rel] = l.Href
+	}
+	assert.Equal(t, map[string]string{
+		"self":      "http://localhost/foo/bar/feed.atom?page=2",
+		"alternate": "http://localhost/foo/bar",
+		"first":     "http://localhost/foo/bar/feed.atom",
+		"last":      "http://localhost/foo/bar/feed.atom?page=3",
+		"previous":  "http://localhost/foo/bar/feed.atom",
+		"next":      "http://localhost/foo/bar/feed.atom?page=3",
+	}, links)
+	assert.Equal(t, "2021-03-04T10:00:00Z", f.Updated)
+
+	require.Len(t, f.Entries, 1)
+	assert.Equal(t, "urn:stargazer:foo/bar:alice", f.Entries[0].ID)
+	assert.Equal(t, "alice starred foo/bar", f.Entries[0].Title)
+	assert.Contains(t, f.Entries[0].Content.Body, `<img src="https://avatars.example.com/alice"`)
+
+	_, err := xml.Marshal(f)
+	require.NoError(t, err)
+
+	f = newAtomFeed("http://localhost/foo/bar/feed.atom", "foo/bar", nil, 1, 0, time.Now())
+	for _, l := range f.Links {
+		assert.NotContains(t, []string{"next", "previous"}, l.Rel)
+	}
+}
+
+func Test_baseURL(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	require.NoError(t, err)
+	_, err = parseTrustedProxies([]string{"invalid"})
+	require.Error(t, err)
+
+	s := &Server{
+		tenants: []config.Tenant{
+			{Name: config.DefaultTenant},
+			{Name: "acme", Hosts: []string{"stars.acme.com", "acme.example.com"}},
+		},
+		trustedProxies: proxies,
+	}
+
+	newRequest := func(url, remoteAddr string) *http.Request {
+		r := httptest.NewRequest("GET", url, nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "evil.example.com")
+		return r
+	}
+
+	// Forwarded headers are ignored from untrusted clients
+	assert.Equal(t, "http://localhost:8080", s.baseURL(newRequest("http://localhost:8080/foo/bar/feed.atom", "203.0.113.5:1234")))
+	assert.Equal(t, "https://evil.example.com", s.baseURL(newRequest("http://localhost:8080/foo/bar/feed.atom", "10.0.0.1:1234")))
+	assert.Equal(t, "https://evil.example.com", s.baseURL(newRequest("http://localhost:8080/foo/bar/feed.atom", "192.168.4.2:1234")))
+
+	// The tenant's host is always used when configured
+	assert.Equal(t, "http://stars.acme.com", s.baseURL(newRequest("http://acme.example.com/foo/bar/feed.atom", "203.0.113.5:1234")))
+	assert.Equal(t, "https://stars.acme.com", s.baseURL(newRequest("http://acme.example.com/foo/bar/feed.atom", "10.0.0.1:1234")))
+}
@@ -12,12 +12,15 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/richardlt/stargazer/config"
 	"github.com/richardlt/stargazer/database"
 )
 
 func (s *Server) homeHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := s.ts.ExecuteTemplate(w, "home", map[string]interface{}{}); err != nil {
+		if err := s.ts.ExecuteTemplate(w, "home", map[string]interface{}{
+			"tenant": s.tenant(r),
+		}); err != nil {
 			logrus.Errorf("%+v", errors.WithStack(err))
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -37,70 +40,220 @@ func (s *Server) repositoryPageHandler() http.HandlerFunc {
 		}
 
 		repoPath := strings.ToLower(organization + "/" + repository)
+		tenant := s.tenant(r)
+
+		// Stats per days can be displayed in another timezone than the one they were generated in
+		loc, err := requestLocation(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 
-		e, err := s.db.Get(repoPath)
+		e, err := s.db.Get(tenant.Name, repoPath)
 		if err != nil && errors.Cause(err) != gorm.ErrRecordNotFound {
 			logrus.Errorf("%+v", errors.WithStack(err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		if e == nil {
-			entriesCount, err := s.db.Count()
-			if err != nil {
-				logrus.Errorf("%+v", errors.WithStack(err))
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if entriesCount >= s.maxEntriesCount {
-				logrus.Warnf("%+v", errors.WithStack(fmt.Errorf("max entries count reached %d/%d", entriesCount, s.maxEntriesCount)))
+
+		// Past stats are only displayed and never trigger a generation
+		at, diff := r.URL.Query().Get("at"), r.URL.Query().Get("diff")
+		if at != "" || diff != "" {
+			if e == nil {
 				w.WriteHeader(http.StatusNotFound)
 				return
 			}
-
-			e = &database.Entry{
-				Repository: repoPath,
-				Status:     database.StatusRequested,
-			}
-			if err := s.db.Create(e); err != nil {
-				logrus.Errorf("%+v", errors.WithStack(err))
-				w.WriteHeader(http.StatusInternalServerError)
+			snapshot, statsDiff, status, err := s.snapshotAndDiff(*e, at, diff)
+			if err != nil {
+				if status == http.StatusInternalServerError {
+					logrus.Errorf("%+v", err)
+				}
+				w.WriteHeader(status)
 				return
 			}
-			logrus.Debugf("New entry created for repository: %s", repoPath)
-		} else {
-			e.LastRequestedAt = time.Now()
-
-			// If stats expired, chnage the status to requested
-			canRefresh := s.regenerateDelay == 0 || e.LastRequestedAt.Sub(e.LastGeneratedAt) > time.Duration(s.regenerateDelay)*time.Second
-			if e.Status == database.StatusGenerated && canRefresh {
-				e.Status = database.StatusRequested
-			}
+			e.Stats = statsInLocation(snapshot.Stats, snapshot.GeneratedAt, loc)
+			e.Status = database.StatusGenerated
+			s.renderRepository(w, tenant, *e, map[string]interface{}{
+				"snapshot_at": snapshot.GeneratedAt.UTC().Format(time.RFC822),
+				"diff":        statsDiff,
+			})
+			return
+		}
 
-			if err := s.db.Update(e); err != nil {
-				logrus.Errorf("%+v", errors.WithStack(err))
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+		e, status, err := s.requestEntry(tenant, repoPath, e)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				logrus.Errorf("%+v", err)
+			} else {
+				logrus.Warnf("%+v", err)
 			}
-			logrus.Debugf("Entry updated for repository: %s", repoPath)
+			w.WriteHeader(status)
+			return
 		}
+		e.Stats = statsInLocation(e.Stats, e.LastGeneratedAt, loc)
 
-		buf, err := json.Marshal(e.Stats)
+		s.renderRepository(w, tenant, *e, nil)
+	}
+}
+
+// requestEntry creates the entry for a repository or updates an existing one, its stats will be generated again if they
+// expired.
+func (s *Server) requestEntry(tenant config.Tenant, repoPath string, e *database.Entry) (*database.Entry, int, error) {
+	if e == nil {
+		entriesCount, err := s.db.Count(tenant.Name)
 		if err != nil {
-			logrus.Errorf("%+v", errors.WithStack(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, http.StatusInternalServerError, err
+		}
+		if entriesCount >= tenant.MaxEntriesCount {
+			return nil, http.StatusNotFound, errors.WithStack(fmt.Errorf("max entries count reached %d/%d for tenant %s", entriesCount, tenant.MaxEntriesCount, tenant.Name))
 		}
 
-		if err := s.ts.ExecuteTemplate(w, "repository", map[string]interface{}{
-			"main_repository":          s.mainRepository,
-			"entry":                    *e,
-			"stats_json":               string(buf),
-			"last_generated_at_string": e.LastGeneratedAt.UTC().Format(time.RFC822),
-			"regenerate_delay_human":   (time.Duration(s.regenerateDelay) * time.Second).String(),
-		}); err != nil {
-			logrus.Errorf("%+v", errors.WithStack(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		token, err := database.NewToken()
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		e = &database.Entry{
+			Tenant:     tenant.Name,
+			Repository: repoPath,
+			Status:     database.StatusRequested,
+			Token:      token,
+		}
+		if err := s.db.Create(e); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		logrus.Debugf("New entry created for repository: %s on tenant %s", repoPath, tenant.Name)
+		return e, http.StatusOK, nil
+	}
+
+	e.LastRequestedAt = time.Now()
+
+	// If stats expired, chnage the status to requested
+	canRefresh := s.regenerateDelay == 0 || e.LastRequestedAt.Sub(e.LastGeneratedAt) > time.Duration(s.regenerateDelay)*time.Second
+	if e.Status == database.StatusGenerated && canRefresh {
+		e.Status = database.StatusRequested
+	}
+
+	if err := s.db.Update(e); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	logrus.Debugf("Entry updated for repository: %s on tenant %s", repoPath, tenant.Name)
+	return e, http.StatusOK, nil
+}
+
+func (s *Server) renderRepository(w http.ResponseWriter, tenant config.Tenant, e database.Entry, extra map[string]interface{}) {
+	buf, err := json.Marshal(e.Stats)
+	if err != nil {
+		logrus.Errorf("%+v", errors.WithStack(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"tenant":                   tenant,
+		"main_repository":          tenant.MainRepository,
+		"eligibility":              s.eligibilityStrategiesMap(),
+		"entry":                    e,
+		"stats_json":               string(buf),
+		"last_generated_at_string": e.LastGeneratedAt.UTC().Format(time.RFC822),
+		"regenerate_delay_human":   (time.Duration(s.regenerateDelay) * time.Second).String(),
+		"timezone":                 s.statsTimezone(e.Stats),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	if err := s.ts.ExecuteTemplate(w, "repository", data); err != nil {
+		logrus.Errorf("%+v", errors.WithStack(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// snapshotAndDiff returns the stats snapshot at given date (current stats if empty) and its diff with the snapshot
+// at diff date if given. Dates are days in stats timezone, the last snapshot generated during the day is used.
+func (s *Server) snapshotAndDiff(e database.Entry, at, diff string) (database.StatsSnapshot, *database.StatsDiff, int, error) {
+	loc, err := time.LoadLocation(s.statsTimezone(e.Stats))
+	if err != nil {
+		loc = time.UTC
+	}
+
+	getAt := func(date string) (database.StatsSnapshot, int, error) {
+		day, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return database.StatsSnapshot{}, http.StatusBadRequest, errors.WithStack(err)
+		}
+		snapshot, err := s.db.GetSnapshotAt(e.ID, day.AddDate(0, 0, 1))
+		if err != nil {
+			if errors.Cause(err) == gorm.ErrRecordNotFound {
+				return database.StatsSnapshot{}, http.StatusNotFound, err
+			}
+			return database.StatsSnapshot{}, http.StatusInternalServerError, err
 		}
+		return *snapshot, http.StatusOK, nil
+	}
+
+	snapshot := database.StatsSnapshot{EntryID: e.ID, GeneratedAt: e.LastGeneratedAt, Stats: e.Stats}
+	if at != "" {
+		var status int
+		snapshot, status, err = getAt(at)
+		if err != nil {
+			return snapshot, nil, status, err
+		}
+	}
+	if diff == "" {
+		return snapshot, nil, http.StatusOK, nil
+	}
+
+	from, status, err := getAt(diff)
+	if err != nil {
+		return snapshot, nil, status, err
+	}
+	d := database.DiffStats(from, snapshot)
+	return snapshot, &d, http.StatusOK, nil
+}
+
+func (s *Server) eligibilityStrategiesMap() map[string]bool {
+	m := make(map[string]bool, len(s.eligibilityStrategies))
+	for _, st := range s.eligibilityStrategies {
+		m[strings.ToLower(strings.TrimSpace(st))] = true
+	}
+	if len(m) == 0 {
+		m["star"] = true
+	}
+	return m
+}
+
+// statsTimezone returns the timezone stats per days are computed in.
+func (s *Server) statsTimezone(stats database.Stats) string {
+	if stats.Timezone != "" {
+		return stats.Timezone
+	}
+	if s.timezone != "" {
+		return s.timezone
+	}
+	return "UTC"
+}
+
+// requestLocation returns the timezone given with the tz query parameter, nil if not given.
+func requestLocation(r *http.Request) (*time.Location, error) {
+	timezone := r.URL.Query().Get("tz")
+	if timezone == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid timezone %s", timezone)
+	}
+	return loc, nil
+}
+
+// statsInLocation returns the stats with stats per days computed in given location, from the counts per hours stored
+// with the stats. Stats generated without counts per hours are returned unchanged.
+func statsInLocation(stats database.Stats, generatedAt time.Time, loc *time.Location) database.Stats {
+	if loc == nil || len(stats.PerHours) == 0 {
+		return stats
 	}
+	stats.PerDays = database.LastDays(stats.PerHours, loc, generatedAt)
+	stats.Timezone = loc.String()
+	return stats
 }
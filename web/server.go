@@ -3,23 +3,54 @@ package web
 import (
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/crawler/github"
 	"github.com/richardlt/stargazer/database"
 )
 
 type Server struct {
-	router          *mux.Router
-	db              *database.DB
-	regenerateDelay int64
-	mainRepository  string
-	maxEntriesCount int64
-	ts              *template.Template
+	router                     *mux.Router
+	db                         *database.DB
+	stargazers                 *database.StargazerStore
+	regenerateDelay            int64
+	tenants                    []config.Tenant
+	timezone                   string
+	eligibilityStrategies      []string
+	corsOrigins                []string
+	trustedProxies             []*net.IPNet
+	githubClient               func(token string) github.Client
+	webhookAllowLocalAddresses bool
+	ts                         *template.Template
+}
+
+// tenant returns the tenant that matches the request's host, or the default tenant.
+func (s *Server) tenant(r *http.Request) config.Tenant {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, t := range s.tenants {
+		for _, h := range t.Hosts {
+			if strings.EqualFold(h, host) {
+				return t
+			}
+		}
+	}
+	for _, t := range s.tenants {
+		if t.Name == config.DefaultTenant {
+			return t
+		}
+	}
+	return config.Tenant{Name: config.DefaultTenant, Title: "Stargazer"}
 }
 
 func (s *Server) initRouter(templatePath string) error {
@@ -33,6 +64,18 @@ func (s *Server) initRouter(templatePath string) error {
 	}
 
 	r := mux.NewRouter()
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(corsMiddleware(s.corsOrigins))
+	api.HandleFunc("/repositories/{organization}/{repository}", s.repositoryAPIHandler()).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	api.HandleFunc("/repositories/{organization}/{repository}/snapshots", s.snapshotsAPIHandler()).Methods(http.MethodGet, http.MethodOptions)
+	api.HandleFunc("/repositories/{organization}/{repository}/token", s.tokenAPIHandler()).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	api.HandleFunc("/repositories/{organization}/{repository}/webhooks", s.webhooksAPIHandler()).Methods(http.MethodPost, http.MethodOptions)
+	api.HandleFunc("/repositories/{organization}/{repository}/webhooks/{id}", s.webhookAPIHandler()).Methods(http.MethodGet, http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/{organization}/{repository}/badge.svg", s.badgeHandler())
+	r.HandleFunc("/{organization}/{repository}/sparkline.svg", s.sparklineHandler())
+	r.HandleFunc("/{organization}/{repository}/feed.atom", s.feedHandler())
+	r.HandleFunc("/{organization}/{repository}/export.{format:csv|ndjson}", s.exportHandler())
+	r.HandleFunc("/{organization}/{repository}/chart/{kind:[a-z-]+}.{format:svg|png}", s.chartHandler())
 	r.HandleFunc("/{organization}/{repository}", s.repositoryPageHandler())
 	r.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) { http.ServeFile(w, r, "./favicon.ico") })
 	r.NotFoundHandler = s.homeHandler()
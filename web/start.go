@@ -1,9 +1,15 @@
 package web
 
 import (
+	"context"
+
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/richardlt/stargazer/config"
+	"github.com/richardlt/stargazer/crawler/github"
 	"github.com/richardlt/stargazer/database"
 )
 
@@ -15,11 +21,30 @@ func Start(cfg config.Web) error {
 		return err
 	}
 
+	client, err := mongo.NewClient(options.Client().ApplyURI(cfg.MgoURI))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := client.Connect(context.Background()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
 	s := &Server{
-		db:              db,
-		regenerateDelay: cfg.RegenerateDelay,
-		mainRepository:  cfg.MainRepository,
-		maxEntriesCount: cfg.MaxEntriesCount,
+		db:                         db,
+		stargazers:                 database.NewStargazerStore(client.Database("stargazer")),
+		regenerateDelay:            cfg.RegenerateDelay,
+		tenants:                    cfg.Tenants,
+		timezone:                   cfg.Timezone,
+		eligibilityStrategies:      cfg.EligibilityStrategies,
+		corsOrigins:                cfg.APICORSOrigins,
+		trustedProxies:             trustedProxies,
+		githubClient:               github.NewClient,
+		webhookAllowLocalAddresses: cfg.WebhookAllowLocalAddresses,
 	}
 	if err := s.initRouter("./"); err != nil {
 		return err